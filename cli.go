@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/PawelPleskaczynski/go-pt/pt"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage:
+  go-pt render [flags] scene.json   render a scene
+  go-pt info [flags] scene.json     print information about a scene
+
+Run "go-pt render -help" to see flags overriding settings from the scene file.
+`)
+}
+
+// parseArgs parses flags which can be placed before and after positional arguments
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+func renderCommand(args []string) error {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: go-pt render [flags] scene.json\n\nFlags override values from the scene file:\n")
+		flags.PrintDefaults()
+	}
+	width := flags.Int("w", 0, "image width in pixels")
+	height := flags.Int("h", 0, "image height in pixels")
+	samples := flags.Int("spp", 0, "samples per pixel")
+	depth := flags.Int("depth", 0, "maximum number of bounces")
+	output := flags.String("o", "", "output file, extension (.png, .ppm) selects format if -format isn't given")
+	format := flags.String("format", "", "output format: png8, png16 or ppm")
+	preview := flags.Bool("preview", false, "use fast preview shading")
+	jitter := flags.Bool("jitter", true, "jitter samples inside pixels")
+	sampler := flags.String("sampler", "", "sampler: independent, stratified, halton or sobol")
+	seed := flags.Int64("seed", 0, "random seed, renders with the same seed are identical")
+	toneMapping := flags.Bool("tonemap", true, "apply tone mapping")
+	bvhOptions := addBVHFlags(flags)
+
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return fmt.Errorf("render needs exactly one scene file, got %d", len(positional))
+	}
+
+	set := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	var settings pt.Settings
+	scene, err := pt.LoadScene(positional[0], func(s *pt.Settings) {
+		if set["w"] {
+			s.Width = *width
+		}
+		if set["h"] {
+			s.Height = *height
+		}
+		if set["spp"] {
+			s.Samples = *samples
+		}
+		if set["depth"] {
+			s.Depth = *depth
+		}
+		if set["o"] {
+			s.Output = *output
+			if !set["format"] {
+				switch strings.ToLower(filepath.Ext(*output)) {
+				case ".png":
+					if s.Format == "ppm" {
+						s.Format = "png16"
+					}
+				case ".ppm":
+					s.Format = "ppm"
+				}
+			}
+		}
+		if set["format"] {
+			s.Format = *format
+		}
+		if set["preview"] {
+			s.Preview = *preview
+		}
+		if set["jitter"] {
+			s.Jitter = *jitter
+		}
+		if set["sampler"] {
+			s.Sampler = *sampler
+		}
+		if set["seed"] {
+			s.Seed = *seed
+		}
+		if set["tonemap"] {
+			s.ToneMapping = *toneMapping
+		}
+		settings = *s
+	})
+	if err != nil {
+		return err
+	}
+	scene.SetBVHOptions(*bvhOptions)
+
+	info := scene.Info()
+	hsize, vsize := settings.Width, settings.Height
+	cpus := runtime.NumCPU()
+	log.Printf("Rendering %d objects (%d triangles) and %d spheres at %dx%d at %d samples on %d cores\n", info.Objects, info.Triangles, info.Spheres, hsize, vsize, settings.Samples, cpus)
+
+	averageFrameTime := time.Duration(0)
+	renderer := pt.Renderer{
+		Workers: cpus,
+		Progress: func(p pt.Progress) {
+			averageFrameTime += p.FrameTime
+			fmt.Printf("\r%.2f%% (% 3d/% 3d) % 15s/frame, % 15s sample time, ETA: % 15s", float64(p.Done)/float64(p.Total)*100, p.Done, p.Total, p.FrameTime, p.FrameTime/time.Duration(vsize*hsize), p.ETA)
+		},
+	}
+
+	// stop rendering on interrupt
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	start := time.Now()
+	film, err := renderer.Render(ctx, scene, settings.Options)
+	println()
+	if err != nil {
+		return err
+	}
+
+	elapsed := time.Since(start)
+	averageFrameTime /= time.Duration(settings.Samples)
+	log.Printf("Rendering took %s\nAverage frame time: %s, average sample time: %s\n", elapsed, averageFrameTime, averageFrameTime/time.Duration(vsize*hsize))
+
+	fmt.Printf("Saving...\n")
+	return film.Save(settings.Output, settings.Format, settings.ToneMapping)
+}
+
+// addBVHFlags adds flags setting BVH build options
+func addBVHFlags(flags *flag.FlagSet) *pt.BVHOptions {
+	opts := pt.DefaultBVHOptions()
+	flags.IntVar(&opts.MaxLeafSize, "bvh-leaf", opts.MaxLeafSize, "maximum number of objects in a BVH leaf")
+	flags.IntVar(&opts.Bins, "bvh-bins", opts.Bins, "number of bins used to find BVH splits")
+	flags.Float64Var(&opts.TraversalCost, "bvh-traversal", opts.TraversalCost, "SAH cost of traversing a BVH node")
+	flags.Float64Var(&opts.IntersectionCost, "bvh-intersection", opts.IntersectionCost, "SAH cost of intersecting an object")
+	flags.IntVar(&opts.MaxDepth, "bvh-depth", opts.MaxDepth, "maximum depth of BVHs")
+	return &opts
+}
+
+func infoCommand(args []string) error {
+	flags := flag.NewFlagSet("info", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: go-pt info [flags] scene.json\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	bvh := flags.Bool("bvh", false, "build BVHs and print their stats")
+	bvhOptions := addBVHFlags(flags)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return fmt.Errorf("info needs exactly one scene file, got %d", len(positional))
+	}
+
+	scene, err := pt.LoadScene(positional[0], nil)
+	if err != nil {
+		return err
+	}
+
+	settings := scene.Settings()
+	fmt.Printf("Scene: %s\n", positional[0])
+	fmt.Printf("Image: %dx%d, %d samples, depth %d, output %s (%s)\n", settings.Width, settings.Height, settings.Samples, settings.Depth, settings.Output, settings.Format)
+
+	info := scene.Info()
+	fmt.Printf("Objects: %d (%d triangles), spheres: %d\n", info.Objects, info.Triangles, info.Spheres)
+	if info.Instances > 0 {
+		fmt.Printf("Instances: %d (%d triangles)\n", info.Instances, info.InstancedTriangles)
+	}
+	if info.Empty {
+		fmt.Printf("Bounds: empty scene\n")
+	} else {
+		fmt.Printf("Bounds: min %v, max %v\n", info.Min, info.Max)
+	}
+	fmt.Printf("Environment: %s\n", info.Environment)
+
+	fmt.Printf("Materials: %d\n", len(info.Materials))
+	for _, m := range info.Materials {
+		fmt.Printf("  %s: %v\n", m.Name, m.Material)
+	}
+
+	if *bvh {
+		scene.SetBVHOptions(*bvhOptions)
+		stats := scene.BuildBVH()
+		fmt.Printf("BVH: %d nodes, %d leaves, %d objects\n", stats.Nodes, stats.Leaves, stats.Objects)
+		fmt.Printf("  average leaf size: %.2f, max depth: %d, SAH cost: %.2f\n", stats.AverageLeafSize, stats.MaxDepth, stats.SAHCost)
+	}
+	return nil
+}
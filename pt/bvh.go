@@ -0,0 +1,340 @@
+package pt
+
+import (
+	"math"
+	"sort"
+)
+
+// BVH is a bounding volume hierarchy over any primitives implementing Hittable.
+// BVH is Hittable itself, so BVHs of objects can be used as bottom-level structures
+// of a top-level BVH. Nodes are stored in depth-first order, so the left child of a node
+// directly follows it
+type BVH struct {
+	nodes   []bvhNode
+	objects []Hittable
+}
+
+// bvhNode is a node of a flattened BVH. Leaves hold objects[offset:offset+count],
+// interior nodes have count 0, their right child at offset and are split along axis
+type bvhNode struct {
+	bounds AABB
+	offset int32
+	count  int32
+	axis   int32
+}
+
+// maxBVHDepth is the size of the traversal stack, deeper trees can't be built
+const maxBVHDepth = 64
+
+// BVHOptions controls how BVHs are built using surface area heuristic (SAH)
+type BVHOptions struct {
+	// MaxLeafSize is the largest number of objects in a leaf, bigger nodes are always split
+	MaxLeafSize int
+	// Bins is the number of bins along the split axis in which split positions are evaluated
+	Bins int
+	// TraversalCost and IntersectionCost are costs of visiting a node and intersecting an object
+	TraversalCost, IntersectionCost float64
+	// MaxDepth limits depth of the tree, nodes at this depth become leaves. It can't be more than 64
+	MaxDepth int
+}
+
+// DefaultBVHOptions returns options used unless they are changed with Scene.SetBVHOptions
+func DefaultBVHOptions() BVHOptions {
+	return BVHOptions{
+		MaxLeafSize:      8,
+		Bins:             16,
+		TraversalCost:    1,
+		IntersectionCost: 1,
+		MaxDepth:         64,
+	}
+}
+
+// BVHStats describes quality of built BVHs
+type BVHStats struct {
+	Nodes, Leaves, Objects int
+	MaxDepth               int
+	AverageLeafSize        float64
+	// SAHCost is the expected cost of a ray traversing the tree according to surface area heuristic
+	SAHCost float64
+}
+
+// add combines stats of two BVHs, costs of separate trees add up since all of them are traversed
+func (stats BVHStats) add(other BVHStats) BVHStats {
+	stats.Nodes += other.Nodes
+	stats.Leaves += other.Leaves
+	stats.Objects += other.Objects
+	if other.MaxDepth > stats.MaxDepth {
+		stats.MaxDepth = other.MaxDepth
+	}
+	stats.SAHCost += other.SAHCost
+	if stats.Leaves > 0 {
+		stats.AverageLeafSize = float64(stats.Objects) / float64(stats.Leaves)
+	}
+	return stats
+}
+
+// bvhItem caches bounds of an object while building a tree
+type bvhItem struct {
+	object   Hittable
+	bounds   AABB
+	centroid Tuple
+}
+
+type bvhBin struct {
+	bounds AABB
+	count  int
+}
+
+func emptyBox() AABB {
+	return AABB{
+		Tuple{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64, 0},
+		Tuple{-math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64, 0},
+	}
+}
+
+// getBoundingBox returns a box enclosing all objects
+func getBoundingBox(objects []Hittable) AABB {
+	aabb := emptyBox()
+	for _, object := range objects {
+		aabb = aabb.union(object.boundingBox())
+	}
+	return aabb
+}
+
+// bvhBuilder builds a flattened BVH, items are partitioned in place so every node
+// refers to a contiguous range of them
+type bvhBuilder struct {
+	opts  BVHOptions
+	items []bvhItem
+	nodes []bvhNode
+}
+
+// getBVH builds a BVH using binned SAH, splitting positions are evaluated
+// on bounds of object centroids along their longest axis
+func getBVH(objects []Hittable, opts BVHOptions) *BVH {
+	if opts.MaxLeafSize < 1 {
+		opts.MaxLeafSize = 1
+	}
+	if opts.Bins < 2 {
+		opts.Bins = 2
+	}
+	if opts.MaxDepth > maxBVHDepth {
+		opts.MaxDepth = maxBVHDepth
+	}
+	if len(objects) == 0 {
+		return &BVH{}
+	}
+
+	builder := bvhBuilder{opts: opts, items: make([]bvhItem, len(objects))}
+	for i, object := range objects {
+		box := object.boundingBox()
+		builder.items[i] = bvhItem{object, box, box.center()}
+	}
+	builder.build(0, len(objects), 0)
+
+	tree := &BVH{nodes: builder.nodes, objects: make([]Hittable, len(objects))}
+	for i, item := range builder.items {
+		tree.objects[i] = item.object
+	}
+	return tree
+}
+
+// build appends the node containing items[start:end] and its children
+func (builder *bvhBuilder) build(start, end, depth int) {
+	opts, items := &builder.opts, builder.items[start:end]
+	bounds, centroidBounds := emptyBox(), emptyBox()
+	for _, item := range items {
+		bounds = bounds.union(item.bounds)
+		centroidBounds = centroidBounds.union(AABB{item.centroid, item.centroid})
+	}
+
+	n := len(items)
+	if n <= 1 || depth >= opts.MaxDepth {
+		builder.leaf(start, end, bounds)
+		return
+	}
+
+	axis := centroidBounds.longestAxis()
+	minC, extent := centroidBounds.min.axis(axis), centroidBounds.max.axis(axis)-centroidBounds.min.axis(axis)
+
+	// all centroids in the same place, splitting by position isn't possible
+	if extent <= 0 {
+		if n <= opts.MaxLeafSize {
+			builder.leaf(start, end, bounds)
+			return
+		}
+		builder.median(start, end, depth, axis, bounds)
+		return
+	}
+
+	bins := make([]bvhBin, opts.Bins)
+	for i := range bins {
+		bins[i].bounds = emptyBox()
+	}
+	binIndex := func(item bvhItem) int {
+		b := int(float64(opts.Bins) * (item.centroid.axis(axis) - minC) / extent)
+		if b >= opts.Bins {
+			b = opts.Bins - 1
+		}
+		return b
+	}
+	for _, item := range items {
+		b := binIndex(item)
+		bins[b].count++
+		bins[b].bounds = bins[b].bounds.union(item.bounds)
+	}
+
+	// sweep from the right to get areas of right halves, then from the left to evaluate splits
+	rightArea := make([]float64, opts.Bins)
+	rightCount := make([]int, opts.Bins)
+	box, count := emptyBox(), 0
+	for i := opts.Bins - 1; i > 0; i-- {
+		box = box.union(bins[i].bounds)
+		count += bins[i].count
+		rightArea[i], rightCount[i] = box.surfaceArea(), count
+	}
+
+	bestSplit, bestCost := -1, math.MaxFloat64
+	box, count = emptyBox(), 0
+	for i := 1; i < opts.Bins; i++ {
+		box = box.union(bins[i-1].bounds)
+		count += bins[i-1].count
+		if count == 0 || rightCount[i] == 0 {
+			continue
+		}
+		cost := float64(count)*box.surfaceArea() + float64(rightCount[i])*rightArea[i]
+		if cost < bestCost {
+			bestSplit, bestCost = i, cost
+		}
+	}
+
+	area := bounds.surfaceArea()
+	leafCost := opts.IntersectionCost * float64(n)
+	splitCost := opts.TraversalCost
+	if area > 0 {
+		splitCost += opts.IntersectionCost * bestCost / area
+	}
+	if bestSplit == -1 || (splitCost >= leafCost && n <= opts.MaxLeafSize) {
+		if n <= opts.MaxLeafSize {
+			builder.leaf(start, end, bounds)
+			return
+		}
+		builder.median(start, end, depth, axis, bounds)
+		return
+	}
+
+	// partition items in place so that items from bins left of the split come first
+	mid := 0
+	for i := range items {
+		if binIndex(items[i]) < bestSplit {
+			items[i], items[mid] = items[mid], items[i]
+			mid++
+		}
+	}
+	builder.node(start, start+mid, end, depth, axis, bounds)
+}
+
+// median splits items in half after sorting them by centroid along the axis
+func (builder *bvhBuilder) median(start, end, depth, axis int, bounds AABB) {
+	items := builder.items[start:end]
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].centroid.axis(axis) < items[j].centroid.axis(axis)
+	})
+	builder.node(start, (start+end)/2, end, depth, axis, bounds)
+}
+
+// node appends an interior node splitting items at mid
+func (builder *bvhBuilder) node(start, mid, end, depth, axis int, bounds AABB) {
+	index := len(builder.nodes)
+	builder.nodes = append(builder.nodes, bvhNode{bounds: bounds, axis: int32(axis)})
+	builder.build(start, mid, depth+1)
+	builder.nodes[index].offset = int32(len(builder.nodes))
+	builder.build(mid, end, depth+1)
+}
+
+func (builder *bvhBuilder) leaf(start, end int, bounds AABB) {
+	builder.nodes = append(builder.nodes, bvhNode{bounds: bounds, offset: int32(start), count: int32(end - start)})
+}
+
+// boundingBox returns bounds of the root node
+func (tree *BVH) boundingBox() AABB {
+	if len(tree.nodes) == 0 {
+		return emptyBox()
+	}
+	return tree.nodes[0].bounds
+}
+
+// stats returns quality of the tree, SAH cost is relative to the root node
+func (tree *BVH) stats(opts BVHOptions) BVHStats {
+	stats := BVHStats{}
+	if len(tree.nodes) == 0 {
+		return stats
+	}
+	rootArea := tree.nodes[0].bounds.surfaceArea()
+	var walk func(index, depth int)
+	walk = func(index, depth int) {
+		node := &tree.nodes[index]
+		stats.Nodes++
+		if depth > stats.MaxDepth {
+			stats.MaxDepth = depth
+		}
+		probability := 1.0
+		if rootArea > 0 {
+			probability = node.bounds.surfaceArea() / rootArea
+		}
+		if node.count > 0 {
+			stats.Leaves++
+			stats.Objects += int(node.count)
+			stats.SAHCost += probability * opts.IntersectionCost * float64(node.count)
+			return
+		}
+		stats.SAHCost += probability * opts.TraversalCost
+		walk(index+1, depth+1)
+		walk(int(node.offset), depth+1)
+	}
+	walk(0, 0)
+	if stats.Leaves > 0 {
+		stats.AverageLeafSize = float64(stats.Objects) / float64(stats.Leaves)
+	}
+	return stats
+}
+
+// hit finds the closest hit in the tree. Nodes are visited front to back, the nearer child
+// first, and nodes further than the closest hit so far are skipped
+func (tree *BVH) hit(r Ray, tMin, tMax float64, rec *HitRecord) bool {
+	if len(tree.nodes) == 0 {
+		return false
+	}
+	invDir := Tuple{1 / r.direction.x, 1 / r.direction.y, 1 / r.direction.z, 0}
+	negative := [3]bool{invDir.x < 0, invDir.y < 0, invDir.z < 0}
+
+	var stack [maxBVHDepth]int32
+	top, index := 0, int32(0)
+	hitAnything := false
+	closestSoFar := tMax
+	for {
+		node := &tree.nodes[index]
+		if node.bounds.hitInverse(r.origin, invDir, tMin, closestSoFar) {
+			if node.count == 0 {
+				if negative[node.axis] {
+					stack[top], index = index+1, node.offset
+				} else {
+					stack[top], index = node.offset, index+1
+				}
+				top++
+				continue
+			}
+			for _, object := range tree.objects[node.offset : node.offset+node.count] {
+				if object.hit(r, tMin, closestSoFar, rec) {
+					hitAnything = true
+					closestSoFar = rec.t
+				}
+			}
+		}
+		if top == 0 {
+			return hitAnything
+		}
+		top--
+		index = stack[top]
+	}
+}
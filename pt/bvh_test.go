@@ -0,0 +1,175 @@
+package pt
+
+import (
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// meshSphere returns a sphere tessellated into 2*rings*segments triangles
+func meshSphere(center Tuple, radius float64, rings, segments int) []Triangle {
+	point := func(i, j int) Tuple {
+		theta := math.Pi * float64(i) / float64(rings)
+		phi := 2 * math.Pi * float64(j) / float64(segments)
+		return Tuple{
+			center.x + radius*math.Sin(theta)*math.Cos(phi),
+			center.y + radius*math.Cos(theta),
+			center.z + radius*math.Sin(theta)*math.Sin(phi),
+			0,
+		}
+	}
+	material := NewLambertian(NewConstant(Color{0.5, 0.5, 0.5}))
+	triangles := []Triangle{}
+	for i := 0; i < rings; i++ {
+		for j := 0; j < segments; j++ {
+			p00, p01, p10, p11 := point(i, j), point(i, j+1), point(i+1, j), point(i+1, j+1)
+			for _, position := range []TrianglePosition{{p00, p10, p11}, {p00, p11, p01}} {
+				edge1 := position.vertex1.Subtract(position.vertex0)
+				edge2 := position.vertex2.Subtract(position.vertex0)
+				normal := edge1.Cross(edge2).Normalize()
+				if normal.Dot(position.vertex0.Subtract(center)) < 0 {
+					position.vertex1, position.vertex2 = position.vertex2, position.vertex1
+					normal = normal.Negate()
+				}
+				triangles = append(triangles, Triangle{position: position, material: material, normal: normal, geometric: normal})
+			}
+		}
+	}
+	return triangles
+}
+
+func benchmarkScene(kind string) *Scene {
+	generator := rand.New(rand.NewSource(1))
+	scene := NewScene()
+	material := NewLambertian(NewConstant(Color{0.5, 0.5, 0.5}))
+	if kind == "spheres" || kind == "mixed" {
+		for i := 0; i < 500; i++ {
+			center := Tuple{generator.Float64()*20 - 10, generator.Float64() * 4, generator.Float64()*20 - 10, 0}
+			scene.AddSphere(center, 0.1+generator.Float64()*0.4, material)
+		}
+	}
+	if kind == "meshes" || kind == "mixed" {
+		for x := -1; x <= 1; x++ {
+			for z := -1; z <= 1; z++ {
+				center := Tuple{float64(x) * 6, 2, float64(z) * 6, 0}
+				scene.triangles = append(scene.triangles, meshSphere(center, 2, 48, 96))
+			}
+		}
+	}
+	if kind == "objects" {
+		for i := 0; i < 1000; i++ {
+			center := Tuple{generator.Float64()*20 - 10, generator.Float64() * 4, generator.Float64()*20 - 10, 0}
+			scene.triangles = append(scene.triangles, meshSphere(center, 0.1+generator.Float64()*0.4, 4, 8))
+		}
+	}
+	return scene
+}
+
+// benchmarkRays returns rays from points around the scene towards random points inside it
+func benchmarkRays(n int) []Ray {
+	generator := rand.New(rand.NewSource(2))
+	rays := make([]Ray, n)
+	for i := range rays {
+		angle := generator.Float64() * 2 * math.Pi
+		origin := Tuple{25 * math.Cos(angle), 2 + generator.Float64()*10, 25 * math.Sin(angle), 0}
+		target := Tuple{generator.Float64()*20 - 10, generator.Float64() * 4, generator.Float64()*20 - 10, 0}
+		rays[i] = Ray{origin, target.Subtract(origin).Normalize()}
+	}
+	return rays
+}
+
+// sceneObjects returns all spheres and triangles of the scene
+func sceneObjects(scene *Scene) []Hittable {
+	objects := []Hittable{}
+	for i := range scene.spheres {
+		objects = append(objects, &scene.spheres[i])
+	}
+	for _, mesh := range scene.triangles {
+		for i := range mesh {
+			objects = append(objects, &mesh[i])
+		}
+	}
+	return objects
+}
+
+// checkHits compares hits found by the hittable to testing every object
+func checkHits(t *testing.T, hittable Hittable, objects []Hittable) {
+	t.Helper()
+	for i, r := range benchmarkRays(1000) {
+		var got, expected HitRecord
+		hit := hittable.hit(r, Epsilon, math.MaxFloat64, &got)
+		expectedHit := false
+		closest := math.MaxFloat64
+		for _, object := range objects {
+			if object.hit(r, Epsilon, closest, &expected) {
+				expectedHit, closest = true, expected.t
+			}
+		}
+		if hit != expectedHit || (hit && got.t != expected.t) {
+			t.Errorf("ray %d: got hit %v at %v, expected %v at %v", i, hit, got.t, expectedHit, expected.t)
+		}
+	}
+}
+
+func TestBVHHit(t *testing.T) {
+	objects := sceneObjects(benchmarkScene("mixed"))
+	opts := DefaultBVHOptions()
+	tree := getBVH(objects, opts)
+	if stats := tree.stats(opts); stats.Objects != len(objects) {
+		t.Fatalf("BVH has %d objects, expected %d", stats.Objects, len(objects))
+	}
+	checkHits(t, tree, objects)
+}
+
+func TestTwoLevelBVHHit(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	scene := benchmarkScene("mixed")
+	scene.buildWorld()
+	meshes := scene.meshes
+	checkHits(t, scene.world.bvh, sceneObjects(scene))
+
+	// adding a sphere rebuilds only the top-level BVH
+	scene.AddSphere(Tuple{0, 2, 0, 0}, 5, NewLambertian(NewConstant(Color{1, 1, 1})))
+	scene.buildWorld()
+	for i := range meshes {
+		if scene.meshes[i] != meshes[i] {
+			t.Errorf("BVH of object %d was rebuilt", i)
+		}
+	}
+	checkHits(t, scene.world.bvh, sceneObjects(scene))
+}
+
+func benchmarkHit(b *testing.B, kind string) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	scene := benchmarkScene(kind)
+	scene.buildWorld()
+	rays := benchmarkRays(4096)
+	rec := HitRecord{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scene.world.hit(rays[i%len(rays)], Epsilon, math.MaxFloat64, &rec)
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "rays/s")
+}
+
+func BenchmarkHitSpheres(b *testing.B) {
+	benchmarkHit(b, "spheres")
+}
+
+func BenchmarkHitMeshes(b *testing.B) {
+	benchmarkHit(b, "meshes")
+}
+
+func BenchmarkHitObjects(b *testing.B) {
+	benchmarkHit(b, "objects")
+}
+
+func BenchmarkHitMixed(b *testing.B) {
+	benchmarkHit(b, "mixed")
+}
@@ -1,27 +1,18 @@
-package main
+package pt
 
 import (
 	"math"
-	"math/rand"
 )
 
+// Camera generates rays for pixels of the image
 type Camera struct {
 	origin, lowerLeftCorner, horizontal, vertical, u, v, w Tuple
 	lensRadius                                             float64
 }
 
-func randomDisk(generator rand.Rand) Tuple {
-	var p Tuple
-	for {
-		p = Tuple{RandFloat(generator), RandFloat(generator), 0, 0}.MulScalar(2.0).Subtract(Tuple{1, 1, 0, 0})
-		if p.Dot(p) <= 1.0 {
-			break
-		}
-	}
-	return p
-}
-
-func getCamera(lookFrom, lookAt, up Tuple, fLength, aspect, fNumber, focusDistance float64) Camera {
+// NewCamera returns a camera at lookFrom looking at lookAt, fLength is focal length in mm
+// of a lens on 36x24 mm sensor, aperture is controlled by fNumber
+func NewCamera(lookFrom, lookAt, up Tuple, fLength, aspect, fNumber, focusDistance float64) Camera {
 	var c Camera
 	aperture := (fLength / 1000) / fNumber
 	c.lensRadius = aperture / 2
@@ -39,8 +30,8 @@ func getCamera(lookFrom, lookAt, up Tuple, fLength, aspect, fNumber, focusDistan
 	return c
 }
 
-func (c Camera) getRay(s, t float64, generator rand.Rand) Ray {
-	randomDisk := randomDisk(generator).MulScalar(c.lensRadius)
+func (c Camera) getRay(s, t float64, sampler Sampler) Ray {
+	randomDisk := sampleDisk(sampler.Get2D()).MulScalar(c.lensRadius)
 	offset := c.u.MulScalar(randomDisk.x).Add(c.v.MulScalar(randomDisk.y))
 	return Ray{c.origin.Add(offset), c.lowerLeftCorner.Add(c.horizontal.MulScalar(s)).Add(c.vertical.MulScalar(t)).Subtract(c.origin).Subtract(offset)}
 }
@@ -1,10 +1,30 @@
-package main
+package pt
+
+import (
+	"fmt"
+	"math"
+)
 
 // Color struct holds three color values
 type Color struct {
 	r, g, b float64
 }
 
+// NewColor returns a color with given values
+func NewColor(r, g, b float64) Color {
+	return Color{r, g, b}
+}
+
+// RGB returns color values
+func (c Color) RGB() (float64, float64, float64) {
+	return c.r, c.g, c.b
+}
+
+// String returns color values formatted as (r, g, b)
+func (c Color) String() string {
+	return fmt.Sprintf("(%.3g, %.3g, %.3g)", c.r, c.g, c.b)
+}
+
 // Add adds color values from other color values
 func (c Color) Add(c1 Color) Color {
 	return Color{c.r + c1.r, c.g + c1.g, c.b + c1.b}
@@ -30,6 +50,7 @@ func (c Color) Mul(c1 Color) Color {
 	return Color{c.r * c1.r, c.g * c1.g, c.b * c1.b}
 }
 
+// Hex returns a color from 0xrrggbb value
 func Hex(c int) Color {
 	return Color{float64(c>>16&0xff) / 255, float64(c>>8&0xff) / 255, float64(c&0xff) / 255}
 }
@@ -44,3 +65,14 @@ func (cIn Color) changeLuminance(lOut float64) Color {
 	lIn := cIn.Luminance()
 	return cIn.MulScalar(lOut / lIn)
 }
+
+// sqrt returns the square root of every channel, the tint of a single crossing of a surface
+// which colors light passing through an object with c
+func (c Color) sqrt() Color {
+	return Color{math.Sqrt(c.r), math.Sqrt(c.g), math.Sqrt(c.b)}
+}
+
+// mean returns the mean of the channels
+func (c Color) mean() float64 {
+	return (c.r + c.g + c.b) / 3
+}
@@ -0,0 +1,99 @@
+package pt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Conductor is the complex index of refraction eta + ik of a metal for red, green and blue light
+type Conductor struct {
+	Eta, K Color
+}
+
+// conductors are measured indices of refraction of metals at wavelengths of about 650, 550 and 450 nm
+var conductors = map[string]Conductor{
+	"gold":      {Color{0.143119, 0.374957, 1.44248}, Color{3.98316, 2.38572, 1.60322}},
+	"silver":    {Color{0.155265, 0.116723, 0.138342}, Color{4.82835, 3.12225, 2.14696}},
+	"copper":    {Color{0.200438, 0.924033, 1.10221}, Color{3.91295, 2.45285, 2.14219}},
+	"aluminium": {Color{1.65746, 0.880369, 0.521229}, Color{9.22387, 6.26952, 4.837}},
+	"chromium":  {Color{4.36968, 2.9167, 1.6547}, Color{5.20643, 4.23136, 3.75495}},
+	"iron":      {Color{2.9114, 2.9497, 2.5845}, Color{3.0893, 2.9318, 2.767}},
+	"titanium":  {Color{2.7407, 2.5418, 2.267}, Color{3.8143, 3.4345, 3.0385}},
+}
+
+// ConductorPreset returns the measured index of refraction of gold, silver, copper, aluminium,
+// chromium, iron or titanium
+func ConductorPreset(name string) (Conductor, bool) {
+	c, ok := conductors[name]
+	return c, ok
+}
+
+// conductorNames returns names of conductor presets in alphabetical order
+func conductorNames() []string {
+	names := make([]string, 0, len(conductors))
+	for name := range conductors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EdgeTint returns a conductor with the given reflectivity at normal incidence, edge tint colors
+// reflection near grazing angles (Gulbrandsen 2014, Artist Friendly Metallic Fresnel)
+func EdgeTint(reflectivity, edgeTint Color) Conductor {
+	channel := func(r, g float64) (eta, k float64) {
+		r = math.Max(0, math.Min(0.99, r))
+		g = math.Max(0, math.Min(1, g))
+		nMin := (1 - r) / (1 + r)
+		nMax := (1 + math.Sqrt(r)) / (1 - math.Sqrt(r))
+		eta = g*nMin + (1-g)*nMax
+		k2 := ((eta+1)*(eta+1)*r - (eta-1)*(eta-1)) / (1 - r)
+		return eta, math.Sqrt(math.Max(0, k2))
+	}
+	var c Conductor
+	c.Eta.r, c.K.r = channel(reflectivity.r, edgeTint.r)
+	c.Eta.g, c.K.g = channel(reflectivity.g, edgeTint.g)
+	c.Eta.b, c.K.b = channel(reflectivity.b, edgeTint.b)
+	return c
+}
+
+// NewConductor returns a metal with Fresnel reflectance of the conductor, its albedo is white,
+// roughness and clearcoat are the same as in NewMetal
+func NewConductor(c Conductor, roughness, clearcoat, clearcoatRoughness float64) Material {
+	m := NewMetal(NewConstant(Color{1, 1, 1}), roughness, clearcoat, clearcoatRoughness)
+	m.conductor = &c
+	return m
+}
+
+// String returns the index of refraction of the conductor
+func (c Conductor) String() string {
+	return fmt.Sprintf("eta %v, k %v", c.Eta, c.K)
+}
+
+// fresnel returns reflectance of the conductor for light arriving at cosine cos with the normal
+func (c Conductor) fresnel(cos float64) Color {
+	return Color{
+		frConductor(cos, c.Eta.r, c.K.r),
+		frConductor(cos, c.Eta.g, c.K.g),
+		frConductor(cos, c.Eta.b, c.K.b),
+	}
+}
+
+// frConductor returns Fresnel reflectance of unpolarized light on a conductor with complex index of
+// refraction eta + ik, cosI is the cosine of the incident direction with the normal
+func frConductor(cosI, eta, k float64) float64 {
+	cosI = math.Max(0, math.Min(1, math.Abs(cosI)))
+	cos2 := cosI * cosI
+	sin2 := 1 - cos2
+	t0 := eta*eta - k*k - sin2
+	a2b2 := math.Sqrt(t0*t0 + 4*eta*eta*k*k)
+	a := math.Sqrt(math.Max(0, (a2b2+t0)/2))
+	t1 := a2b2 + cos2
+	t2 := 2 * cosI * a
+	perpendicular := (t1 - t2) / (t1 + t2)
+	t3 := cos2*a2b2 + sin2*sin2
+	t4 := t2 * sin2
+	parallel := perpendicular * (t3 - t4) / (t3 + t4)
+	return (parallel + perpendicular) / 2
+}
@@ -0,0 +1,61 @@
+package pt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFrConductor(t *testing.T) {
+	// without absorption a conductor is a dielectric
+	for _, cos := range []float64{1, 0.8, 0.5, 0.2, 0.01} {
+		if f, expected := frConductor(cos, 1.5, 0), frDielectric(cos, 1.5); math.Abs(f-expected) > 1e-9 {
+			t.Errorf("reflectance %v at cosine %v with k 0, expected %v", f, cos, expected)
+		}
+	}
+	// at normal incidence reflectance is ((eta-1)^2 + k^2) / ((eta+1)^2 + k^2)
+	eta, k := 0.2, 3.9
+	if f, expected := frConductor(1, eta, k), ((eta-1)*(eta-1)+k*k)/((eta+1)*(eta+1)+k*k); !Equal(f, expected) {
+		t.Errorf("reflectance %v at normal incidence, expected %v", f, expected)
+	}
+	if f := frConductor(0, eta, k); !Equal(f, 1) {
+		t.Errorf("reflectance %v at grazing angle, expected 1", f)
+	}
+}
+
+func TestEdgeTint(t *testing.T) {
+	reflectivity, edgeTint := Color{0.95, 0.64, 0.54}, Color{1, 0.8, 0.7}
+	f := EdgeTint(reflectivity, edgeTint).fresnel(1)
+	if math.Abs(f.r-reflectivity.r) > 1e-6 || math.Abs(f.g-reflectivity.g) > 1e-6 || math.Abs(f.b-reflectivity.b) > 1e-6 {
+		t.Errorf("reflectance %v at normal incidence, expected reflectivity %v", f, reflectivity)
+	}
+}
+
+func TestConductorPresets(t *testing.T) {
+	for _, name := range conductorNames() {
+		c, _ := ConductorPreset(name)
+		for _, cos := range []float64{1, 0.5, 0.1} {
+			f := c.fresnel(cos)
+			for _, v := range []float64{f.r, f.g, f.b} {
+				if v <= 0 || v > 1 {
+					t.Errorf("%s reflects %v at cosine %v", name, f, cos)
+				}
+			}
+		}
+	}
+	// copper and gold are red and yellow, silver is almost white
+	copper, _ := ConductorPreset("copper")
+	if f := copper.fresnel(1); !(f.r > f.g && f.g > f.b) {
+		t.Errorf("copper reflects %v at normal incidence", f)
+	}
+	gold, _ := ConductorPreset("gold")
+	if f := gold.fresnel(1); !(f.r > f.b && f.g > f.b) {
+		t.Errorf("gold reflects %v at normal incidence", f)
+	}
+	silver, _ := ConductorPreset("silver")
+	if f := silver.fresnel(1); f.b < 0.9 {
+		t.Errorf("silver reflects %v at normal incidence", f)
+	}
+	if _, ok := ConductorPreset("unobtainium"); ok {
+		t.Error("unknown conductor found")
+	}
+}
@@ -0,0 +1,19 @@
+// Package pt is a Monte Carlo path tracer running on CPU.
+//
+// A scene can be loaded from a JSON scene file with LoadScene or built in code:
+//
+//	scene := pt.NewScene()
+//	scene.SetCamera(pt.NewCamera(pt.NewTuple(-5, 1.25, -5), pt.NewTuple(0, 0.8, 0), pt.NewTuple(0, 1, 0), 40, 4.0/3.0, 1024, 7))
+//	scene.AddSphere(pt.NewTuple(0, 0.2, 0), 0.2, pt.NewLambertian(pt.NewConstant(pt.NewColor(1, 1, 1))))
+//	scene.SetSky(pt.NewTuple(1, 0.5, 0))
+//
+//	renderer := pt.Renderer{}
+//	film, err := renderer.Render(context.Background(), scene, pt.Options{Width: 512, Height: 384, Samples: 64, Depth: 8, Jitter: true})
+//	if err != nil {
+//		return err
+//	}
+//	return film.Save("out.png", "png16", true)
+//
+// Radiance HDR images can be loaded only if their decoder is registered by importing
+// github.com/mdouchement/hdr/codec/rgbe.
+package pt
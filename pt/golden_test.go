@@ -0,0 +1,158 @@
+package pt
+
+import (
+	"context"
+	"flag"
+	"image/png"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden images in testdata/golden")
+
+// goldenTolerance is the largest root mean square error between a render and its golden image,
+// in gamma corrected values from 0 to 1
+const goldenTolerance = 0.02
+
+// goldenScene is a small canonical scene rendered by TestGolden
+type goldenScene struct {
+	name  string
+	scene func() *Scene
+	opts  Options
+}
+
+var goldenScenes = []goldenScene{
+	{"materials", materialsScene, Options{Width: 64, Height: 48, Samples: 32, Depth: 8, Jitter: true}},
+	{"meshes", meshesScene, Options{Width: 64, Height: 48, Samples: 32, Depth: 8, Jitter: true}},
+	{"textures", texturesScene, Options{Width: 64, Height: 48, Samples: 16, Depth: 4, Jitter: true}},
+	{"sky", skyScene, Options{Width: 48, Height: 32, Samples: 8, Depth: 4, Jitter: true}},
+	{"preview", materialsScene, Options{Width: 64, Height: 48, Samples: 4, Depth: 4, Jitter: true, Preview: true}},
+}
+
+// materialsScene has a sphere of every kind of material on a checkerboard floor
+func materialsScene() *Scene {
+	scene := NewScene()
+	scene.SetCamera(NewCamera(Tuple{0, 1.5, -6, 0}, Tuple{0, 0.5, 0, 0}, Tuple{0, 1, 0, 0}, 35, 4.0/3.0, 1024, 6))
+	scene.AddSphere(Tuple{0, -1000, 0, 0}, 1000, NewLambertian(NewCheckerboard(Color{0.8, 0.8, 0.8}, Color{0.2, 0.2, 0.2}, 1, 1, 1)))
+	scene.AddSphere(Tuple{-2.4, 0.6, 0, 0}, 0.6, NewLambertian(NewConstant(Color{0.8, 0.2, 0.2})))
+	scene.AddSphere(Tuple{-1.2, 0.6, 0, 0}, 0.6, NewMetal(NewConstant(Color{0.9, 0.7, 0.3}), 0.2, 0, 0))
+	scene.AddSphere(Tuple{0, 0.6, 0, 0}, 0.6, NewDielectric(NewConstant(Color{1, 1, 1}), 0, 0, 1.5))
+	scene.AddSphere(Tuple{1.2, 0.6, 0, 0}, 0.6, NewGlossy(NewConstant(Color{0.2, 0.3, 0.8}), 0.3, 1))
+	scene.AddSphere(Tuple{2.4, 0.6, 0, 0}, 0.6, NewBSDF(NewConstant(Color{0.3, 0.8, 0.3}), 0.4, 1.5, 0.5, 0.1, 0.3, 0))
+	scene.SetEnvironment(NewConstant(Color{0.8, 0.85, 1}))
+	return scene
+}
+
+// meshesScene has triangle meshes, an instance and an emissive sphere
+func meshesScene() *Scene {
+	scene := NewScene()
+	scene.SetCamera(NewCamera(Tuple{0, 3, -7, 0}, Tuple{0, 0.5, 0, 0}, Tuple{0, 1, 0, 0}, 35, 4.0/3.0, 1024, 7))
+	scene.AddSphere(Tuple{0, -1000, 0, 0}, 1000, NewLambertian(NewConstant(Color{0.6, 0.6, 0.6})))
+	scene.AddSphere(Tuple{0, 4, 0, 0}, 1, NewEmission(NewConstant(Color{4, 4, 4})))
+	scene.triangles = append(scene.triangles, meshSphere(Tuple{-1.5, 1, 0, 0}, 1, 8, 16))
+	mesh := &Mesh{triangles: [][]Triangle{meshSphere(Tuple{0, 0, 0, 0}, 1, 12, 24)}}
+	material := NewMetal(NewConstant(Color{0.9, 0.9, 0.9}), 0.1, 0, 0)
+	scene.AddInstance(mesh, Scale(0.7, 1.2, 0.7).Then(RotateZ(0.4)).Then(Translate(1.5, 1.1, 0)), &material)
+	scene.SetEnvironment(NewConstant(Color{0.4, 0.4, 0.5}))
+	return scene
+}
+
+// texturesScene has procedural textures mapped with UVs and coordinates
+func texturesScene() *Scene {
+	scene := NewScene()
+	scene.SetCamera(NewCamera(Tuple{0, 2, -5, 0}, Tuple{0, 0.5, 0, 0}, Tuple{0, 1, 0, 0}, 35, 4.0/3.0, 1024, 5))
+	scene.AddSphere(Tuple{0, -1000, 0, 0}, 1000, NewLambertian(NewGrid(Color{0.9, 0.9, 0.9}, Color{0.1, 0.1, 0.1}, 0.5, 0.5, 0.5, 0.05)))
+	scene.AddSphere(Tuple{-1, 0.8, 0, 0}, 0.8, NewLambertian(NewCheckerboardUV(Color{0.9, 0.1, 0.1}, Color{0.9, 0.9, 0.9}, 8, 4)))
+	scene.AddSphere(Tuple{1, 0.8, 0, 0}, 0.8, NewLambertian(NewGridUV(Color{0.1, 0.1, 0.9}, Color{0.9, 0.9, 0.9}, 8, 4, 0.1)))
+	scene.SetEnvironment(NewConstant(Color{1, 1, 1}))
+	return scene
+}
+
+// skyScene is lit by the Nishita sky
+func skyScene() *Scene {
+	scene := NewScene()
+	scene.SetCamera(NewCamera(Tuple{0, 1, -5, 0}, Tuple{0, 1.5, 0, 0}, Tuple{0, 1, 0, 0}, 24, 1.5, 1024, 5))
+	scene.AddSphere(Tuple{0, -1000, 0, 0}, 1000, NewLambertian(NewConstant(Color{0.5, 0.5, 0.5})))
+	scene.AddSphere(Tuple{0, 1, 0, 0}, 1, NewLambertian(NewConstant(Color{0.8, 0.8, 0.8})))
+	scene.SetSky(Tuple{1, 0.3, 0.5, 0}.Normalize())
+	return scene
+}
+
+// TestGolden renders canonical scenes with the default seed and compares them to images in testdata/golden,
+// run "go test -run Golden -update" to replace the images after an intended change of renders
+func TestGolden(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	for _, golden := range goldenScenes {
+		golden := golden
+		t.Run(golden.name, func(t *testing.T) {
+			var renderer Renderer
+			film, err := renderer.Render(context.Background(), golden.scene(), golden.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join("testdata", "golden", golden.name+".png")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := film.Save(path, "png16", false); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			rmse, err := compareGolden(film, path)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create golden images)", err)
+			}
+			if math.IsNaN(rmse) || rmse > goldenTolerance {
+				failed := filepath.Join(os.TempDir(), "golden-"+golden.name+".png")
+				if err := film.Save(failed, "png16", false); err == nil {
+					t.Logf("render saved to %s", failed)
+				}
+				t.Errorf("render differs from %s, RMSE %.4f is more than %.4f", path, rmse, goldenTolerance)
+			}
+		})
+	}
+}
+
+// compareGolden returns root mean square error between the film and the golden image,
+// using the same gamma correction as Film.Save without tone mapping
+func compareGolden(film *Film, path string) (float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	reference, err := png.Decode(f)
+	if err != nil {
+		return 0, err
+	}
+	bounds := reference.Bounds()
+	if bounds.Dx() != film.Width() || bounds.Dy() != film.Height() {
+		return math.Inf(1), nil
+	}
+
+	gamma := func(v float64) float64 {
+		return math.Sqrt(math.Min(v, 1))
+	}
+	sum := 0.0
+	for y := 0; y < film.Height(); y++ {
+		for x := 0; x < film.Width(); x++ {
+			c := film.At(x, y)
+			r, g, b, _ := reference.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			dr := gamma(c.r) - float64(r)/65535
+			dg := gamma(c.g) - float64(g)/65535
+			db := gamma(c.b) - float64(b)/65535
+			sum += dr*dr + dg*dg + db*db
+		}
+	}
+	return math.Sqrt(sum / float64(3*film.Width()*film.Height())), nil
+}
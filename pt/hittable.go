@@ -0,0 +1,146 @@
+package pt
+
+import (
+	"math"
+)
+
+type HitRecord struct {
+	u, v, t float64
+	uT, vT  float64
+	p       Tuple
+	normal  Tuple
+	// tangent points in the direction of increasing texture coordinate u, it orients
+	// normal maps and anisotropic materials
+	tangent  Tuple
+	material Material
+	// geometric is the normal of the surface without smoothing and normal maps, sphere is
+	// the hit sphere, they are used to find probability densities of sampling lights
+	geometric Tuple
+	sphere    *Sphere
+	// interpolated is the normal of triangles before normal maps, materials of instances
+	// apply their own normal maps to it instead of the ones of meshes
+	interpolated Tuple
+	// key is a random number of the path sample, hashed with rays by stochastic cutouts and mixes
+	// so that they differ between samples, it's set before the search for the hit
+	key uint64
+}
+
+// frame returns the shading frame at the hit, with w along the normal and u along the tangent
+func (rec HitRecord) frame() ONB {
+	return buildFromWU(rec.normal, rec.tangent)
+}
+
+// Hittable is implemented by every primitive which can be intersected by rays
+type Hittable interface {
+	hit(r Ray, tMin, tMax float64, rec *HitRecord) bool
+	boundingBox() AABB
+}
+
+// HittableList holds the top-level BVH of a scene and atmospheres
+type HittableList struct {
+	bvh *BVH
+	atm []Atmosphere
+}
+
+type AABB struct {
+	min, max Tuple
+}
+
+func (h *HittableList) hit(r Ray, tMin, tMax float64, rec *HitRecord) bool {
+	if h.bvh == nil {
+		return false
+	}
+	return h.bvh.hit(r, tMin, tMax, rec)
+}
+
+// union returns a box enclosing both boxes
+func (box AABB) union(other AABB) AABB {
+	return AABB{
+		Tuple{math.Min(box.min.x, other.min.x), math.Min(box.min.y, other.min.y), math.Min(box.min.z, other.min.z), 0},
+		Tuple{math.Max(box.max.x, other.max.x), math.Max(box.max.y, other.max.y), math.Max(box.max.z, other.max.z), 0},
+	}
+}
+
+// surfaceArea returns surface area of the box
+func (box AABB) surfaceArea() float64 {
+	x, y, z := box.max.x-box.min.x, box.max.y-box.min.y, box.max.z-box.min.z
+	if x < 0 || y < 0 || z < 0 {
+		return 0
+	}
+	return 2 * (x*y + y*z + z*x)
+}
+
+// longestAxis returns 0, 1 or 2 for x, y or z axis
+func (box AABB) longestAxis() int {
+	x, y, z := box.sizeX(), box.sizeY(), box.sizeZ()
+	if x >= y && x >= z {
+		return 0
+	} else if y >= z {
+		return 1
+	}
+	return 2
+}
+
+// center returns the centroid of the box
+func (box AABB) center() Tuple {
+	return box.min.Add(box.max).MulScalar(0.5)
+}
+
+func (box *AABB) hit(r Ray, tMin, tMax float64) bool {
+	invDir := Tuple{1 / r.direction.x, 1 / r.direction.y, 1 / r.direction.z, 0}
+	return box.hitInverse(r.origin, invDir, tMin, tMax)
+}
+
+// hitInverse checks if a ray with the given origin and inverse of direction
+// hits the box between tMin and tMax
+func (box *AABB) hitInverse(origin, invDir Tuple, tMin, tMax float64) bool {
+	t0 := (box.min.x - origin.x) * invDir.x
+	t1 := (box.max.x - origin.x) * invDir.x
+	if invDir.x < 0 {
+		t0, t1 = t1, t0
+	}
+	if t0 > tMin {
+		tMin = t0
+	}
+	if t1 < tMax {
+		tMax = t1
+	}
+
+	t0 = (box.min.y - origin.y) * invDir.y
+	t1 = (box.max.y - origin.y) * invDir.y
+	if invDir.y < 0 {
+		t0, t1 = t1, t0
+	}
+	if t0 > tMin {
+		tMin = t0
+	}
+	if t1 < tMax {
+		tMax = t1
+	}
+
+	t0 = (box.min.z - origin.z) * invDir.z
+	t1 = (box.max.z - origin.z) * invDir.z
+	if invDir.z < 0 {
+		t0, t1 = t1, t0
+	}
+	if t0 > tMin {
+		tMin = t0
+	}
+	if t1 < tMax {
+		tMax = t1
+	}
+
+	return tMin <= tMax
+}
+
+func (box *AABB) sizeX() float64 {
+	return math.Abs(box.max.x - box.min.x)
+}
+
+func (box *AABB) sizeY() float64 {
+	return math.Abs(box.max.y - box.min.y)
+}
+
+func (box *AABB) sizeZ() float64 {
+	return math.Abs(box.max.z - box.min.z)
+}
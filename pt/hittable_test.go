@@ -0,0 +1,42 @@
+package pt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAABBHit(t *testing.T) {
+	box := AABB{Tuple{-1, -1, -1, 0}, Tuple{1, 1, 1, 0}}
+	tests := []struct {
+		name       string
+		ray        Ray
+		tMin, tMax float64
+		hit        bool
+	}{
+		{"through", Ray{Tuple{0, 0, -5, 0}, Tuple{0, 0, 1, 0}}, 0, math.MaxFloat64, true},
+		{"diagonal", Ray{Tuple{-5, -5, -5, 0}, Tuple{1, 1, 1, 0}}, 0, math.MaxFloat64, true},
+		{"negative direction", Ray{Tuple{0.5, 0.5, 5, 0}, Tuple{0, 0, -1, 0}}, 0, math.MaxFloat64, true},
+		{"from inside", Ray{Tuple{0, 0, 0, 0}, Tuple{1, 0, 0, 0}}, 0, math.MaxFloat64, true},
+		{"miss", Ray{Tuple{0, 2, -5, 0}, Tuple{0, 0, 1, 0}}, 0, math.MaxFloat64, false},
+		{"parallel outside", Ray{Tuple{2, 0, -5, 0}, Tuple{0, 0, 1, 0}}, 0, math.MaxFloat64, false},
+		{"parallel inside", Ray{Tuple{0.5, 0.5, -5, 0}, Tuple{0, 0, 1, 0}}, 0, math.MaxFloat64, true},
+		{"behind", Ray{Tuple{0, 0, 5, 0}, Tuple{0, 0, 1, 0}}, 0, math.MaxFloat64, false},
+		{"before tMin", Ray{Tuple{0, 0, -5, 0}, Tuple{0, 0, 1, 0}}, 7, math.MaxFloat64, false},
+		{"after tMax", Ray{Tuple{0, 0, -5, 0}, Tuple{0, 0, 1, 0}}, 0, 3, false},
+	}
+	for _, test := range tests {
+		if hit := box.hit(test.ray, test.tMin, test.tMax); hit != test.hit {
+			t.Errorf("%s: got hit %v, expected %v", test.name, hit, test.hit)
+		}
+	}
+}
+
+func TestAABBSurfaceArea(t *testing.T) {
+	box := AABB{Tuple{0, 0, 0, 0}, Tuple{1, 2, 3, 0}}
+	if area := box.surfaceArea(); !Equal(area, 22) {
+		t.Errorf("got surface area %v, expected 22", area)
+	}
+	if area := emptyBox().surfaceArea(); area != 0 {
+		t.Errorf("got surface area %v of an empty box, expected 0", area)
+	}
+}
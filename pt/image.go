@@ -1,4 +1,4 @@
-package main
+package pt
 
 import (
 	"bufio"
@@ -15,14 +15,8 @@ const (
 	PNG
 )
 
-// checks if there's an error
-func check(e error) {
-	if e != nil {
-		panic(e)
-	}
-}
-
-func SaveImage(canvas []Color, width, height, maxValue int, fileName string, extension int, depth int, toneMapping bool) {
+// SaveImage saves the canvas to a PPM or PNG file, fileName is given without extension
+func SaveImage(canvas []Color, width, height, maxValue int, fileName string, extension int, depth int, toneMapping bool) error {
 	maxLum := 0.0
 	if toneMapping {
 		for y := height - 1; y >= 0; y-- {
@@ -62,25 +56,40 @@ func SaveImage(canvas []Color, width, height, maxValue int, fileName string, ext
 
 	if extension == PPM {
 		f, err := os.Create(fileName + ".ppm")
-		check(err)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
 
 		w := bufio.NewWriter(f)
-		defer w.Flush()
 
 		_, err = fmt.Fprintf(w, "P3\n%d %d\n%d\n", width, height, maxValue)
-		check(err)
+		if err != nil {
+			return err
+		}
 
 		for y := height - 1; y >= 0; y-- {
 			for x := 0; x < width; x++ {
 				_, err := fmt.Fprintf(w, "%d %d %d ", int(math.Sqrt(canvas[y*width+x].r)*255), int(math.Sqrt(canvas[y*width+x].g)*255), int(math.Sqrt(canvas[y*width+x].b)*255))
-				check(err)
+				if err != nil {
+					return err
+				}
 			}
 			_, err := fmt.Fprint(w, "\n")
-			check(err)
+			if err != nil {
+				return err
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
 		}
+		return f.Close()
 	} else if extension == PNG {
 		f, err := os.Create(fileName + ".png")
-		check(err)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
 		if depth == 8 {
 			image := image.NewRGBA(image.Rect(0, 0, width, height))
 			for y := height - 1; y >= 0; y-- {
@@ -88,7 +97,9 @@ func SaveImage(canvas []Color, width, height, maxValue int, fileName string, ext
 					image.SetRGBA(x, height-1-y, color.RGBA{uint8(math.Sqrt(canvas[y*width+x].r) * 255.9), uint8(math.Sqrt(canvas[y*width+x].g) * 255.9), uint8(math.Sqrt(canvas[y*width+x].b) * 255.9), 255})
 				}
 			}
-			png.Encode(f, image)
+			if err := png.Encode(f, image); err != nil {
+				return err
+			}
 		} else {
 			image := image.NewRGBA64(image.Rect(0, 0, width, height))
 			for y := height - 1; y >= 0; y-- {
@@ -96,7 +107,11 @@ func SaveImage(canvas []Color, width, height, maxValue int, fileName string, ext
 					image.SetRGBA64(x, height-1-y, color.RGBA64{uint16(math.Sqrt(canvas[y*width+x].r) * 65535.9), uint16(math.Sqrt(canvas[y*width+x].g) * 65535.9), uint16(math.Sqrt(canvas[y*width+x].b) * 65535.9), 65535})
 				}
 			}
-			png.Encode(f, image)
+			if err := png.Encode(f, image); err != nil {
+				return err
+			}
 		}
+		return f.Close()
 	}
+	return fmt.Errorf("unknown image format %d", extension)
 }
@@ -0,0 +1,89 @@
+package pt
+
+// Mesh is a model loaded once with Scene.LoadMesh, which can be placed
+// in the scene any number of times with Scene.AddInstance
+type Mesh struct {
+	triangles [][]Triangle
+	bvh       *BVH
+}
+
+// Triangles returns the number of triangles of the mesh
+func (mesh *Mesh) Triangles() int {
+	n := 0
+	for _, object := range mesh.triangles {
+		n += len(object)
+	}
+	return n
+}
+
+// bounds returns the bounding box of all triangles of the mesh
+func (mesh *Mesh) bounds() AABB {
+	box := emptyBox()
+	for i := range mesh.triangles {
+		for j := range mesh.triangles[i] {
+			box = box.union(mesh.triangles[i][j].boundingBox())
+		}
+	}
+	return box
+}
+
+// build builds the BVH shared by all instances of the mesh
+func (mesh *Mesh) build(opts BVHOptions) {
+	objects := []Hittable{}
+	for i := range mesh.triangles {
+		for j := range mesh.triangles[i] {
+			objects = append(objects, &mesh.triangles[i][j])
+		}
+	}
+	mesh.bvh = getBVH(objects, opts)
+}
+
+// Instance is a mesh placed in the scene with its own transformation,
+// rays are transformed to object space of the mesh instead of copying its triangles
+type Instance struct {
+	mesh      *Mesh
+	transform Transform
+	// material overrides materials of the mesh if not nil
+	material *Material
+}
+
+func (inst *Instance) boundingBox() AABB {
+	return inst.transform.box(inst.mesh.bvh.boundingBox())
+}
+
+func (inst *Instance) hit(r Ray, tMin, tMax float64, rec *HitRecord) bool {
+	// direction isn't normalized, so distances along the ray are the same in both spaces
+	local := inst.transform.Inverse().ray(r)
+	if inst.material != nil && inst.material.opacity != nil {
+		// the material of the instance cuts out triangles too, the search continues behind them
+		hit := HitRecord{key: rec.key}
+		for {
+			if !inst.mesh.bvh.hit(local, tMin, tMax, &hit) {
+				return false
+			}
+			hit.p = r.Position(hit.t)
+			if !inst.material.cutout(r, hit) {
+				break
+			}
+			tMin = hit.t
+		}
+		*rec = hit
+	} else if !inst.mesh.bvh.hit(local, tMin, tMax, rec) {
+		return false
+	}
+	rec.p = r.Position(rec.t)
+	rec.normal = inst.transform.Normal(rec.normal).Normalize()
+	rec.geometric = inst.transform.Normal(rec.geometric).Normalize()
+	rec.tangent = inst.transform.Vector(rec.tangent)
+	if inst.material != nil {
+		// normal maps of the mesh's materials are replaced by the one of the instance
+		rec.normal = inst.transform.Normal(rec.interpolated).Normalize()
+		rec.interpolated = rec.normal
+		rec.material = *inst.material
+		if len(rec.material.albedo.normalTexture) > 0 {
+			uvw := rec.frame()
+			rec.normal = uvw.local(rec.material.albedo.normal(*rec)).Normalize()
+		}
+	}
+	return true
+}
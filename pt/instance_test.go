@@ -0,0 +1,97 @@
+package pt
+
+import (
+	"math"
+	"testing"
+)
+
+// TestInstanceHit compares hits of an instance to hits of the same mesh with transformed triangles
+func TestInstanceHit(t *testing.T) {
+	mesh := &Mesh{triangles: [][]Triangle{meshSphere(Tuple{0, 0, 0, 0}, 1, 16, 32)}}
+	mesh.build(DefaultBVHOptions())
+	transform := Scale(4, 1, 2).Then(RotateY(0.7)).Then(Translate(1, 2, -3))
+	instance := &Instance{mesh: mesh, transform: transform}
+
+	baked := make([]Triangle, len(mesh.triangles[0]))
+	objects := make([]Hittable, len(baked))
+	for i, tri := range mesh.triangles[0] {
+		tri.position.vertex0 = transform.Point(tri.position.vertex0)
+		tri.position.vertex1 = transform.Point(tri.position.vertex1)
+		tri.position.vertex2 = transform.Point(tri.position.vertex2)
+		edge1 := tri.position.vertex1.Subtract(tri.position.vertex0)
+		edge2 := tri.position.vertex2.Subtract(tri.position.vertex0)
+		tri.normal = edge1.Cross(edge2).Normalize()
+		tri.geometric = tri.normal
+		baked[i] = tri
+		objects[i] = &baked[i]
+	}
+
+	hits := 0
+	for i, r := range benchmarkRays(1000) {
+		var got, expected HitRecord
+		hit := instance.hit(r, Epsilon, math.MaxFloat64, &got)
+		expectedHit := false
+		closest := math.MaxFloat64
+		for _, object := range objects {
+			if object.hit(r, Epsilon, closest, &expected) {
+				expectedHit, closest = true, expected.t
+			}
+		}
+		if hit != expectedHit {
+			t.Errorf("ray %d: got hit %v, expected %v", i, hit, expectedHit)
+			continue
+		}
+		if !hit {
+			continue
+		}
+		hits++
+		if math.Abs(got.t-expected.t) > 1e-9*expected.t {
+			t.Errorf("ray %d: got hit at %v, expected %v", i, got.t, expected.t)
+		}
+		if !got.p.Equals(expected.p) {
+			t.Errorf("ray %d: got hit point %v, expected %v", i, got.p, expected.p)
+		}
+		if got.normal.Dot(expected.normal) < 1-1e-9 {
+			t.Errorf("ray %d: got normal %v, expected %v", i, got.normal, expected.normal)
+		}
+	}
+	if hits == 0 {
+		t.Fatal("no ray hit the instance")
+	}
+}
+
+// TestInstanceNormalMap checks that the normal map of an instance's material replaces the one of
+// the mesh's material instead of perturbing the normal again
+func TestInstanceNormalMap(t *testing.T) {
+	tilted := func(c Color) Material {
+		m := NewLambertian(NewConstant(Color{0.8, 0.8, 0.8}))
+		m.albedo.normalTexture = [][]Color{{c}}
+		return m
+	}
+	tri := Triangle{
+		position:  TrianglePosition{Tuple{-1, -1, 0, 0}, Tuple{1, -1, 0, 0}, Tuple{0, 1, 0, 0}},
+		vtexture:  TrianglePosition{Tuple{0, 0, 0, 0}, Tuple{1, 0, 0, 0}, Tuple{0.5, 1, 0, 0}},
+		normal:    Tuple{0, 0, 1, 0},
+		geometric: Tuple{0, 0, 1, 0},
+		material:  tilted(Color{0.8, 0.5, 0.9}),
+	}
+	override, plain := tilted(Color{0.5, 0.8, 0.9}), NewLambertian(NewConstant(Color{0.8, 0.8, 0.8}))
+	mesh := &Mesh{triangles: [][]Triangle{{tri}}}
+	mesh.build(DefaultBVHOptions())
+	r := Ray{Tuple{0, 0, 1, 0}, Tuple{0, 0, -1, 0}}
+	for _, test := range []struct {
+		name     string
+		material *Material
+	}{{"override with a normal map", &override}, {"override without a normal map", &plain}} {
+		instance := &Instance{mesh: mesh, transform: Identity(), material: test.material}
+		expected := tri
+		expected.material = *test.material
+		var got, want HitRecord
+		if !instance.hit(r, Epsilon, math.MaxFloat64, &got) || !expected.hit(r, Epsilon, math.MaxFloat64, &want) {
+			t.Fatalf("%s: the ray missed the triangle", test.name)
+		}
+		if got.normal.Dot(want.normal.Normalize()) < 1-1e-9 {
+			t.Errorf("%s: got normal %v, expected %v", test.name, got.normal, want.normal.Normalize())
+		}
+	}
+}
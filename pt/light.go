@@ -0,0 +1,268 @@
+package pt
+
+import (
+	"math"
+	"sort"
+)
+
+// light is an emissive object sampled directly by next event estimation
+type light interface {
+	// sample returns the direction from p towards a point on the light, the distance to the
+	// point, radiance emitted towards p and the probability density of the direction in
+	// solid angle, which is zero if the light can't be sampled from p
+	sample(p Tuple, u, v float64) (wi Tuple, dist float64, emitted Color, pdf float64)
+	// power is proportional to the power of the light, lights are chosen in proportion to it
+	power() float64
+}
+
+// shadowEpsilon shortens shadow rays relative to their length, so they don't hit the light itself
+const shadowEpsilon = 1e-5
+
+// lightList holds emissive objects of the scene
+type lightList struct {
+	lights []light
+	// cdf is the cumulative distribution of choosing lights, total is their total power
+	cdf   []float64
+	total float64
+	// spheres are probabilities of choosing sphere lights
+	spheres map[*Sphere]float64
+}
+
+func newLightList(lights []light) lightList {
+	list := lightList{lights: lights, cdf: make([]float64, len(lights)), spheres: map[*Sphere]float64{}}
+	for i, l := range lights {
+		list.total += l.power()
+		list.cdf[i] = list.total
+	}
+	for i := range list.cdf {
+		if list.total > 0 {
+			list.cdf[i] /= list.total
+		} else {
+			list.cdf[i] = float64(i+1) / float64(len(lights))
+		}
+	}
+	for i, l := range lights {
+		if s, ok := l.(sphereLight); ok {
+			list.spheres[s.sphere] = list.probability(i)
+		}
+	}
+	return list
+}
+
+func (list *lightList) probability(i int) float64 {
+	if i > 0 {
+		return list.cdf[i] - list.cdf[i-1]
+	}
+	return list.cdf[i]
+}
+
+// choose returns a light chosen with u in proportion to power and the probability of choosing it
+func (list *lightList) choose(u float64) (light, float64) {
+	i := sort.SearchFloat64s(list.cdf, u)
+	if i >= len(list.cdf) {
+		i = len(list.cdf) - 1
+	}
+	return list.lights[i], list.probability(i)
+}
+
+// pdf returns the probability density of sampling the direction from p to the emissive
+// surface hit in rec, including the probability of choosing the light
+func (list *lightList) pdf(p Tuple, rec *HitRecord) float64 {
+	if rec.sphere != nil {
+		return list.spheres[rec.sphere] * sphereLight{rec.sphere}.pdf(p)
+	}
+	if list.total <= 0 {
+		return 0
+	}
+	// area of the triangle cancels out between the probability of choosing it and the density on it
+	toLight := rec.p.Subtract(p)
+	cosLight := math.Abs(toLight.Normalize().Dot(rec.geometric))
+	if cosLight == 0 {
+		return 0
+	}
+	return emissionPower(rec.material.albedo) / list.total * toLight.Dot(toLight) / cosLight
+}
+
+// emissionPower estimates average emitted luminance of a texture
+func emissionPower(t Texture) float64 {
+	switch t.mode {
+	case SphereImageUV, TriangleImageUV:
+		return 1
+	}
+	sum := 0.0
+	for _, c := range t.c {
+		sum += c.Luminance()
+	}
+	return sum / float64(len(t.c))
+}
+
+// sphereLight is an emissive sphere, points are sampled in the cone of directions it covers
+type sphereLight struct {
+	sphere *Sphere
+}
+
+func (l sphereLight) sample(p Tuple, u, v float64) (Tuple, float64, Color, float64) {
+	s := l.sphere
+	toCenter := s.origin.Subtract(p)
+	dc := toCenter.Magnitude()
+	if dc <= s.radius {
+		return Tuple{}, 0, Color{}, 0
+	}
+
+	oneMinusCosThetaMax := l.oneMinusCosThetaMax(dc)
+	cosTheta := 1 - u*oneMinusCosThetaMax
+	sin2Theta := math.Max(0, 1-cosTheta*cosTheta)
+	sinTheta := math.Sqrt(sin2Theta)
+	phi := 2 * math.Pi * v
+
+	uvw := buildFromW(toCenter)
+	wi := uvw.local(Tuple{sinTheta * math.Cos(phi), sinTheta * math.Sin(phi), cosTheta, 0}).Normalize()
+	dist := dc*cosTheta - math.Sqrt(math.Max(0, s.radius*s.radius-dc*dc*sin2Theta))
+
+	var rec HitRecord
+	rec.p = p.Add(wi.MulScalar(dist))
+	rec.normal = rec.p.Subtract(s.origin).Normalize()
+	rec.u, rec.v = s.uv(rec.p)
+	rec.uT, rec.vT = rec.u, rec.v
+	rec.material = s.material
+	return wi, dist, s.material.albedo.color(rec), 1 / (2 * math.Pi * oneMinusCosThetaMax)
+}
+
+// oneMinusCosThetaMax returns 1 - cosine of the half angle of the cone covered by the sphere
+// seen from distance dc, it's computed as sin^2 / (1 + cos) to stay accurate for small and
+// distant spheres
+func (l sphereLight) oneMinusCosThetaMax(dc float64) float64 {
+	sin2ThetaMax := l.sphere.radius * l.sphere.radius / (dc * dc)
+	cosThetaMax := math.Sqrt(math.Max(0, 1-sin2ThetaMax))
+	return sin2ThetaMax / (1 + cosThetaMax)
+}
+
+// pdf returns the probability density of sampling any direction towards the sphere from p
+func (l sphereLight) pdf(p Tuple) float64 {
+	dc := l.sphere.origin.Subtract(p).Magnitude()
+	if dc <= l.sphere.radius {
+		return 0
+	}
+	return 1 / (2 * math.Pi * l.oneMinusCosThetaMax(dc))
+}
+
+func (l sphereLight) power() float64 {
+	return 4 * math.Pi * l.sphere.radius * l.sphere.radius * emissionPower(l.sphere.material.albedo)
+}
+
+// triangleLight is an emissive triangle in world space, points are sampled uniformly on its area
+type triangleLight struct {
+	triangle Triangle
+	area     float64
+}
+
+func newTriangleLight(tri Triangle) triangleLight {
+	edge1 := tri.position.vertex1.Subtract(tri.position.vertex0)
+	edge2 := tri.position.vertex2.Subtract(tri.position.vertex0)
+	return triangleLight{tri, edge1.Cross(edge2).Magnitude() / 2}
+}
+
+func (l triangleLight) sample(p Tuple, u, v float64) (Tuple, float64, Color, float64) {
+	tri := &l.triangle
+	if l.area == 0 {
+		return Tuple{}, 0, Color{}, 0
+	}
+	// barycentric coordinates of the point as used by Triangle.hit
+	su := math.Sqrt(u)
+	b1 := su * (1 - v)
+	b2 := su * v
+
+	var rec HitRecord
+	rec.p = tri.position.vertex0.MulScalar(1 - b1 - b2).Add(tri.position.vertex1.MulScalar(b1)).Add(tri.position.vertex2.MulScalar(b2))
+	rec.u, rec.v = b1, b2
+	uv := tri.vtexture.vertex0.MulScalar(1 - b1 - b2).Add(tri.vtexture.vertex1.MulScalar(b1)).Add(tri.vtexture.vertex2.MulScalar(b2))
+	rec.uT, rec.vT = uv.x, uv.y
+	rec.normal, rec.geometric = tri.normal, tri.geometric
+	rec.material = tri.material
+
+	toLight := rec.p.Subtract(p)
+	dist := toLight.Magnitude()
+	if dist == 0 {
+		return Tuple{}, 0, Color{}, 0
+	}
+	wi := toLight.DivScalar(dist)
+	// triangles emit light from both sides
+	cosLight := math.Abs(wi.Dot(tri.geometric))
+	if cosLight == 0 {
+		return Tuple{}, 0, Color{}, 0
+	}
+	return wi, dist, tri.material.albedo.color(rec), dist * dist / (cosLight * l.area)
+}
+
+func (l triangleLight) power() float64 {
+	return l.area * emissionPower(l.triangle.material.albedo)
+}
+
+// collectLights returns emissive spheres and triangles of the scene, triangles of instances
+// are transformed to world space
+func (scene *Scene) collectLights() []light {
+	var lights []light
+	for i := range scene.spheres {
+		if scene.spheres[i].material.material == Emission {
+			lights = append(lights, sphereLight{&scene.spheres[i]})
+		}
+	}
+	for _, object := range scene.triangles {
+		for i := range object {
+			if object[i].material.material == Emission {
+				lights = append(lights, newTriangleLight(object[i]))
+			}
+		}
+	}
+	for _, instance := range scene.instances {
+		if instance.material != nil && instance.material.material != Emission {
+			continue
+		}
+		for _, object := range instance.mesh.triangles {
+			for _, tri := range object {
+				if instance.material != nil {
+					tri.material = *instance.material
+				} else if tri.material.material != Emission {
+					continue
+				}
+				tri.position.vertex0 = instance.transform.Point(tri.position.vertex0)
+				tri.position.vertex1 = instance.transform.Point(tri.position.vertex1)
+				tri.position.vertex2 = instance.transform.Point(tri.position.vertex2)
+				tri.normal = instance.transform.Normal(tri.normal).Normalize()
+				tri.geometric = instance.transform.Normal(tri.geometric).Normalize()
+				lights = append(lights, newTriangleLight(tri))
+			}
+		}
+	}
+	return lights
+}
+
+// sampleLight chooses a light with the sampler and samples a point on it, it returns the
+// direction and distance to the point, emitted radiance and the probability density of the
+// direction including the probability of choosing the light
+func (scene *Scene) sampleLight(p Tuple, sampler Sampler) (Tuple, float64, Color, float64) {
+	l, probability := scene.lights.choose(sampler.Get1D())
+	u, v := sampler.Get2D()
+	wi, dist, emitted, pdf := l.sample(p, u, v)
+	return wi, dist, emitted, pdf * probability
+}
+
+// occluded reports if anything is between p and the point at distance dist in direction wi,
+// key is the key of the hit at p, so stochastic cutouts are the same as for rays scattered there
+func (scene *Scene) occluded(p, wi Tuple, dist float64, key uint64) bool {
+	rec := HitRecord{key: key}
+	return scene.world.hit(Ray{p, wi}, Epsilon, dist*(1-shadowEpsilon), &rec)
+}
+
+// powerHeuristic returns the weight of a sample taken with probability density f
+// when the same direction could be sampled with density g by another technique
+func powerHeuristic(f, g float64) float64 {
+	if math.IsInf(f, 1) {
+		return 1
+	}
+	f, g = f*f, g*g
+	if f+g == 0 {
+		return 0
+	}
+	return f / (f + g)
+}
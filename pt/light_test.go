@@ -0,0 +1,233 @@
+package pt
+
+import (
+	"io"
+	"log"
+	"math"
+	"os"
+	"testing"
+)
+
+// TestTriangleLightSolidAngle checks that the inverse of probability densities of sampled
+// directions averages to the solid angle of the triangle and that the light list finds the same
+// densities for hits. The shading normal of the triangle is smoothed, which mustn't change them
+func TestTriangleLightSolidAngle(t *testing.T) {
+	a, b, c := Tuple{-1, 2, 1, 0}, Tuple{2, 2.5, 0, 0}, Tuple{0, 3, 2, 0}
+	edge1, edge2 := b.Subtract(a), c.Subtract(a)
+	l := newTriangleLight(Triangle{
+		position:  TrianglePosition{a, b, c},
+		material:  NewEmission(NewConstant(Color{1, 1, 1})),
+		normal:    Tuple{0, 1, 0, 0},
+		geometric: edge1.Cross(edge2).Normalize(),
+	})
+	list := newLightList([]light{l})
+	p := Tuple{0.2, 0, 0.3, 0}
+
+	// Van Oosterom and Strackee formula
+	ra, rb, rc := a.Subtract(p), b.Subtract(p), c.Subtract(p)
+	la, lb, lc := ra.Magnitude(), rb.Magnitude(), rc.Magnitude()
+	numerator := math.Abs(ra.Dot(rb.Cross(rc)))
+	denominator := la*lb*lc + ra.Dot(rb)*lc + ra.Dot(rc)*lb + rb.Dot(rc)*la
+	expected := 2 * math.Atan2(numerator, denominator)
+
+	sampler, _ := NewSampler("sobol", 4096, 0)
+	sum := 0.0
+	for i := 0; i < 4096; i++ {
+		sampler.StartPixelSample(0, i)
+		wi, dist, emitted, pdf := l.sample(p, sampler.Get1D(), sampler.Get1D())
+		if pdf <= 0 {
+			t.Fatalf("sample %d has probability density %v", i, pdf)
+		}
+		if emitted != (Color{1, 1, 1}) {
+			t.Fatalf("sample %d has emission %v", i, emitted)
+		}
+		var rec HitRecord
+		if !l.triangle.hit(Ray{p, wi}, 0, dist*(1+1e-9), &rec) || math.Abs(rec.t-dist) > 1e-9 {
+			t.Fatalf("sample %d at distance %v isn't on the triangle", i, dist)
+		}
+		if hitPdf := list.pdf(p, &rec); math.Abs(hitPdf-pdf) > 1e-6*pdf {
+			t.Fatalf("sample %d has probability density %v, the light list found %v for its hit", i, pdf, hitPdf)
+		}
+		sum += 1 / pdf
+	}
+	if got := sum / 4096; math.Abs(got-expected) > 1e-3*expected {
+		t.Errorf("got solid angle %v, expected %v", got, expected)
+	}
+}
+
+func TestSphereLightSample(t *testing.T) {
+	s := &Sphere{Tuple{1, 2, 3, 0}, 0.5, NewEmission(NewConstant(Color{1, 1, 1}))}
+	l := sphereLight{s}
+	for _, p := range []Tuple{{0, 0, 0, 0}, {1, 2, 3.6, 0}, {1000, 0, 0, 0}} {
+		dc := s.origin.Subtract(p).Magnitude()
+		expected := 1 / (2 * math.Pi * (1 - math.Sqrt(1-s.radius*s.radius/(dc*dc))))
+		for i := 0; i < 100; i++ {
+			u, v := float64(i%10)/10+0.05, float64(i/10)/10+0.05
+			wi, dist, _, pdf := l.sample(p, u, v)
+			if math.Abs(pdf-expected) > 1e-6*expected {
+				t.Errorf("from %v: got probability density %v, expected %v", p, pdf, expected)
+			}
+			var rec HitRecord
+			if !s.hit(Ray{p, wi}, 0, math.MaxFloat64, &rec) || math.Abs(rec.t-dist) > 1e-6*dist {
+				t.Errorf("from %v: sample at distance %v isn't the closest point of the sphere", p, dist)
+			}
+		}
+	}
+	if _, _, _, pdf := l.sample(s.origin, 0.5, 0.5); pdf != 0 {
+		t.Errorf("sample from inside the sphere has probability density %v", pdf)
+	}
+}
+
+func TestChooseLight(t *testing.T) {
+	dim := &Sphere{Tuple{0, 0, 0, 0}, 1, NewEmission(NewConstant(Color{1, 1, 1}))}
+	bright := &Sphere{Tuple{0, 0, 0, 0}, 1, NewEmission(NewConstant(Color{3, 3, 3}))}
+	list := newLightList([]light{sphereLight{dim}, sphereLight{bright}})
+	if l, probability := list.choose(0.1); l.(sphereLight).sphere != dim || math.Abs(probability-0.25) > 1e-12 {
+		t.Errorf("got light %v with probability %v, expected the first light with 0.25", l, probability)
+	}
+	if l, probability := list.choose(0.9); l.(sphereLight).sphere != bright || math.Abs(probability-0.75) > 1e-12 {
+		t.Errorf("got light %v with probability %v, expected the second light with 0.75", l, probability)
+	}
+}
+
+// misScene has a glossy floor lit by a sphere light and a triangle light
+func misScene() *Scene {
+	scene := NewScene()
+	scene.AddSphere(Tuple{0, -1000, 0, 0}, 1000, NewBSDF(NewConstant(Color{0.5, 0.5, 0.5}), 0.3, 1.5, 0.5, 0.2, 0.3, 0))
+	scene.AddSphere(Tuple{-1, 2, 0, 0}, 0.5, NewEmission(NewConstant(Color{4, 4, 4})))
+	scene.triangles = append(scene.triangles, []Triangle{{
+		position:  TrianglePosition{Tuple{1, 1, -1, 0}, Tuple{2, 1, 1, 0}, Tuple{1, 3, 0, 0}},
+		material:  NewEmission(NewConstant(Color{2, 1, 1})),
+		normal:    Tuple{1, 1, -1, 0}.Normalize(),
+		geometric: Tuple{-4, -1, 2, 0}.Normalize(),
+	}})
+	scene.buildWorld()
+	return scene
+}
+
+// TestMISWeights checks that direct light rendered with weights of light sampling and BSDF sampling
+// converges to estimates of either technique alone, which is the case only if the weights sum to one
+func TestMISWeights(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	scene := misScene()
+	var rec HitRecord
+	r := Ray{Tuple{0, 1, -2, 0}, Tuple{0, -1, 2, 0}}
+	if !scene.world.hit(r, Epsilon, math.MaxFloat64, &rec) {
+		t.Fatal("ray doesn't hit the floor")
+	}
+	wo := r.direction.Normalize().Negate()
+	material := rec.material
+
+	const samples = 1 << 17
+	// a path of two segments reaches lights once, directly or through the BSDF sample
+	opts := Options{Samples: samples, Depth: 2}
+	sampler, _ := NewSampler("independent", samples, 0)
+	var mis, light, bsdf Color
+	for i := 0; i < samples; i++ {
+		sampler.StartPixelSample(0, i)
+		mis = mis.Add(colorize(r, scene, &opts, 0, 0, sampler, nil))
+
+		wi, dist, emitted, pdf := scene.sampleLight(rec.p, sampler)
+		if pdf > 0 && !scene.occluded(rec.p, wi, dist, rec.key) {
+			light = light.Add(material.Eval(rec, wo, wi).Mul(emitted).DivScalar(pdf))
+		}
+
+		var srec ScatterRecord
+		if material.Scatter(r, rec, sampler, &srec) {
+			var hit HitRecord
+			if scene.world.hit(srec.ray, Epsilon, math.MaxFloat64, &hit) && hit.material.material == Emission {
+				bsdf = bsdf.Add(srec.attenuation.Mul(hit.material.albedo.color(hit)))
+			}
+		}
+	}
+	mis, light, bsdf = mis.DivScalar(samples), light.DivScalar(samples), bsdf.DivScalar(samples)
+	for _, estimate := range []struct {
+		technique string
+		c         Color
+	}{{"light sampling", light}, {"BSDF sampling", bsdf}} {
+		for i, expected := range []float64{estimate.c.r, estimate.c.g, estimate.c.b} {
+			got := []float64{mis.r, mis.g, mis.b}[i]
+			if expected <= 0 || math.Abs(got-expected) > 0.03*expected {
+				t.Errorf("got %v with MIS, %v with %s", mis, estimate.c, estimate.technique)
+				break
+			}
+		}
+	}
+}
+
+// TestMixedEmission checks that emission of lights in mixes, which aren't sampled as lights,
+// isn't weighted against light sampling
+func TestMixedEmission(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	scene := NewScene()
+	scene.AddSphere(Tuple{-1, 2, 0, 0}, 0.5, NewEmission(NewConstant(Color{4, 4, 4})))
+	emission := NewEmission(NewConstant(Color{1, 2, 3}))
+	scene.triangles = append(scene.triangles, []Triangle{{
+		position:  TrianglePosition{Tuple{-1, 1, 3, 0}, Tuple{1, 1, 3, 0}, Tuple{0, 3, 3, 0}},
+		material:  NewMix(emission, emission, gray(0.5)),
+		normal:    Tuple{0, 0, -1, 0},
+		geometric: Tuple{0, 0, -1, 0},
+	}})
+	scene.buildWorld()
+
+	opts := Options{Samples: 1, Depth: 4}
+	sampler, _ := NewSampler("independent", 1, 0)
+	// the pdf of a BSDF sample at the previous bounce is given, so the hit would be weighted
+	c := colorize(Ray{Tuple{0, 1.5, 0, 0}, Tuple{0, 0, 1, 0}}, scene, &opts, 1, 1, sampler, nil)
+	if !colorsEqual(c, Color{1, 2, 3}) {
+		t.Errorf("got emission %v, expected %v", c, Color{1, 2, 3})
+	}
+}
+
+// TestEvalPdf checks that Scatter returns attenuation equal to Eval divided by Pdf
+func TestEvalPdf(t *testing.T) {
+	rec := HitRecord{p: Tuple{0, 0, 0, 0}, normal: Tuple{0, 1, 0, 0}, tangent: Tuple{0, 0, 1, 0}}
+	r := Ray{Tuple{1, 1, 0, 0}, Tuple{-1, -1, 0, 0}}
+	wo := r.direction.Normalize().Negate()
+	sampler, _ := NewSampler("independent", 1, 0)
+	equal := func(a, b float64) bool {
+		return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+	}
+	for _, m := range []Material{
+		NewLambertian(NewConstant(Color{0.8, 0.5, 0.2})),
+		NewLambertian(NewConstant(Color{0.8, 0.5, 0.2})).WithOrenNayar(30),
+		NewGlossy(NewConstant(Color{0.8, 0.5, 0.2}), 0.2, 1),
+		NewMetal(NewConstant(Color{0.9, 0.6, 0.3}), 0.4, 0.5, 0.1),
+		NewConductor(conductors["copper"], 0.3, 0.5, 0.1),
+		NewDielectric(NewConstant(Color{0.9, 0.9, 1}), 0.3, 0, 1.5),
+		NewDielectric(NewConstant(Color{0.9, 0.9, 1}), 0.3, 0, 1.5).WithAnisotropy(0.8, 0.2),
+		NewDielectric(NewConstant(Color{0.9, 0.9, 1}), 0.3, 0, 1.5).WithThinFilm(300, 1.38),
+		NewBSDF(NewConstant(Color{0.3, 0.8, 0.3}), 0.4, 1.5, 0.5, 0, 0.3, 0.5).WithThinFilm(600, 1.33),
+		NewBSDF(NewConstant(Color{0.3, 0.8, 0.3}), 0.4, 1.5, 0.5, 0, 0.3, 0.5),
+		NewMix(NewLambertian(NewConstant(Color{0.8, 0.5, 0.2})), NewMetal(NewConstant(Color{0.9, 0.6, 0.3}), 0.3, 0, 0), gray(0.5)),
+		NewLayered(NewMetal(NewConstant(Color{0.9, 0.6, 0.3}), 0.4, 0, 0), 0.2, 1.5, 0.1, Color{0.5, 1, 2}),
+		NewLayered(NewDielectric(NewConstant(Color{0.9, 0.9, 1}), 0.3, 0, 1.5), 0.1, 1.4, 0, Color{0, 0, 0}),
+		principledMaterial(func(p *PrincipledParams) {
+			p.Metallic, p.Sheen, p.Clearcoat, p.Anisotropic, p.SpecTrans = gray(0.3), gray(1), gray(1), gray(0.7), gray(0.3)
+			p.AnisotropicRotation = gray(0.4)
+		}),
+		principledMaterial(func(p *PrincipledParams) {
+			p.Thin, p.Flatness, p.DiffTrans, p.Sheen, p.SpecTrans = gray(1), gray(0.5), gray(0.4), gray(1), gray(0.3)
+		}),
+		principledMaterial(func(p *PrincipledParams) {
+			p.ScatterDistance, p.Sheen, p.Clearcoat, p.Metallic = NewConstant(Color{1, 0.5, 0.2}), gray(1), gray(1), gray(0.2)
+		}),
+	} {
+		rec.material = m
+		for i := 0; i < 500; i++ {
+			sampler.StartPixelSample(0, i)
+			var srec ScatterRecord
+			if !m.Scatter(r, rec, sampler, &srec) || srec.specular {
+				continue
+			}
+			expected := m.Eval(rec, wo, srec.ray.direction).DivScalar(m.Pdf(rec, wo, srec.ray.direction))
+			if !equal(srec.attenuation.r, expected.r) || !equal(srec.attenuation.g, expected.g) || !equal(srec.attenuation.b, expected.b) {
+				t.Fatalf("%v: attenuation %v, expected %v", m, srec.attenuation, expected)
+			}
+		}
+	}
+}
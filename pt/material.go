@@ -0,0 +1,503 @@
+package pt
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	BSDF = iota
+	Lambertian
+	Emission
+	Principled
+	Subsurface
+	Mix
+	Layered
+)
+
+// Material describes how light interacts with a surface
+type Material struct {
+	material           int
+	albedo             Texture
+	roughness          float64
+	ior                float64
+	clearcoat          float64
+	clearcoatRoughness float64
+	metalicity         float64
+	transmission       float64
+	// anisotropic stretches roughness along the tangent, which is rotated around the normal
+	// by anisotropicRotation, a fraction of a full turn
+	anisotropic         float64
+	anisotropicRotation float64
+	// sigma is the standard deviation of slopes of diffuse microfacets in degrees, which make
+	// diffuse reflection rough and retro-reflective (Oren-Nayar), zero for Lambertian reflection
+	sigma float64
+	// film is a thin film on the surface, which colors reflections by interference
+	film thinFilm
+	// absorption is the coefficient of absorption inside dielectrics per unit of distance,
+	// priority chooses the medium where dielectrics overlap, see media
+	absorption Color
+	priority   int
+	// radius is the mean free path of light inside Subsurface materials for every channel
+	radius Color
+	// conductor replaces Schlick's approximation of the metal lobe tinted by albedo with
+	// Fresnel reflectance of a conductor, which is still multiplied by albedo
+	conductor *Conductor
+	// principled holds parameters of Principled materials, albedo is their base color
+	principled *PrincipledParams
+	// maps replace scalar parameters with textures, they're evaluated at every hit by at
+	maps *MaterialMaps
+	// opacity cuts out parts of surfaces of any type, rays pass through them
+	opacity *opacityMask
+	// mix holds the materials of Mix materials, layer the coat and base of Layered ones
+	mix   *mixture
+	layer *coating
+}
+
+// NewLambertian returns a diffuse material
+func NewLambertian(albedo Texture) Material {
+	return Material{material: Lambertian, albedo: albedo, ior: 1.5}
+}
+
+// NewGlossy returns a diffuse material with glossy reflections
+func NewGlossy(albedo Texture, roughness, clearcoat float64) Material {
+	return Material{material: BSDF, albedo: albedo, roughness: roughness, ior: 1.5, clearcoat: clearcoat, clearcoatRoughness: roughness}
+}
+
+// NewDielectric returns a transparent material, such as glass
+func NewDielectric(albedo Texture, roughness, clearcoat, ior float64) Material {
+	return Material{material: BSDF, albedo: albedo, roughness: roughness, ior: ior, clearcoat: clearcoat, clearcoatRoughness: roughness, transmission: 1}
+}
+
+// NewMetal returns a metallic material
+func NewMetal(albedo Texture, roughness, clearcoat, clearcoatRoughness float64) Material {
+	return Material{material: BSDF, albedo: albedo, roughness: roughness, ior: 1.5, clearcoat: clearcoat, clearcoatRoughness: clearcoatRoughness, metalicity: 1}
+}
+
+// WithAnisotropy returns the material with roughness stretched along the tangent by anisotropic from 0 to 1,
+// the tangent is rotated around the normal by rotation, a fraction of a full turn
+func (m Material) WithAnisotropy(anisotropic, rotation float64) Material {
+	m.anisotropic, m.anisotropicRotation = anisotropic, rotation
+	return m
+}
+
+// WithOrenNayar returns the material with rough diffuse reflection of clay, concrete, fabric or the Moon,
+// which reflects light back towards its source. sigma is the standard deviation of slopes of microfacets
+// in degrees, about 20 for rough surfaces. It changes Lambertian materials and the diffuse base of others
+func (m Material) WithOrenNayar(sigma float64) Material {
+	m.sigma = sigma
+	return m
+}
+
+// MaterialMaps are textures of scalar parameters, set ones replace constant parameters of the
+// material at every hit point. Scalar parameters use the red channel of gray textures
+type MaterialMaps struct {
+	Roughness, IOR, Clearcoat, ClearcoatRoughness, Metalicity, Transmission *Texture
+	Anisotropic, AnisotropicRotation, Sigma, FilmThickness, FilmIOR         *Texture
+}
+
+// WithMaps returns the material with textured parameters, set maps replace ones given before
+func (m Material) WithMaps(maps MaterialMaps) Material {
+	merged := MaterialMaps{}
+	if m.maps != nil {
+		merged = *m.maps
+	}
+	all := merged.textures()
+	for i, t := range maps.textures() {
+		if *t != nil {
+			*all[i] = *t
+		}
+	}
+	m.maps = &merged
+	return m
+}
+
+// textures returns pointers to all maps in the order of parameters, so they can be changed together
+func (maps *MaterialMaps) textures() []**Texture {
+	return []**Texture{&maps.Roughness, &maps.IOR, &maps.Clearcoat, &maps.ClearcoatRoughness,
+		&maps.Metalicity, &maps.Transmission, &maps.Anisotropic, &maps.AnisotropicRotation, &maps.Sigma,
+		&maps.FilmThickness, &maps.FilmIOR}
+}
+
+// parameters returns pointers to scalar parameters replaced by maps in the same order
+func (m *Material) parameters() []*float64 {
+	return []*float64{&m.roughness, &m.ior, &m.clearcoat, &m.clearcoatRoughness,
+		&m.metalicity, &m.transmission, &m.anisotropic, &m.anisotropicRotation, &m.sigma,
+		&m.film.thickness, &m.film.ior}
+}
+
+func (maps *MaterialMaps) String() string {
+	names := []string{"roughness", "ior", "clearcoat", "clearcoat roughness", "metalicity", "transmission", "anisotropic", "anisotropic rotation", "sigma",
+		"film thickness", "film ior"}
+	var set []string
+	for i, t := range maps.textures() {
+		if *t != nil {
+			set = append(set, fmt.Sprintf("%s %v", names[i], **t))
+		}
+	}
+	return strings.Join(set, ", ")
+}
+
+// at returns the material with its maps evaluated at the hit point
+func (m Material) at(rec HitRecord) Material {
+	if m.maps == nil {
+		return m
+	}
+	parameters := m.parameters()
+	for i, t := range m.maps.textures() {
+		if *t != nil {
+			*parameters[i] = (*t).value(rec)
+		}
+	}
+	m.maps = nil
+	if m.layer != nil {
+		// roughness and ior of Layered materials are those of the coat
+		layer := *m.layer
+		layer.roughness, layer.ior = m.roughness, m.ior
+		m.layer = &layer
+	}
+	return m
+}
+
+// NewEmission returns a light emitting material, albedo is the emitted color
+func NewEmission(albedo Texture) Material {
+	return Material{material: Emission, albedo: albedo}
+}
+
+// NewBSDF returns the universal material with all properties set explicitly
+func NewBSDF(albedo Texture, roughness, ior, clearcoat, clearcoatRoughness, metalicity, transmission float64) Material {
+	return Material{material: BSDF, albedo: albedo, roughness: roughness, ior: ior, clearcoat: clearcoat,
+		clearcoatRoughness: clearcoatRoughness, metalicity: metalicity, transmission: transmission}
+}
+
+// String returns a short description of the material
+func (m Material) String() string {
+	if m.opacity != nil {
+		opaque := m
+		opaque.opacity = nil
+		return fmt.Sprintf("%v, opacity %v", opaque, m.opacity)
+	}
+	switch m.material {
+	case Lambertian:
+		if m.sigma != 0 {
+			return fmt.Sprintf("lambertian, albedo %v, oren-nayar sigma %g", m.albedo, m.sigma)
+		}
+		return fmt.Sprintf("lambertian, albedo %v", m.albedo)
+	case Emission:
+		return fmt.Sprintf("emission, color %v", m.albedo)
+	case BSDF:
+		description := fmt.Sprintf("bsdf, albedo %v, roughness %g, ior %g, clearcoat %g (roughness %g), metalicity %g, transmission %g",
+			m.albedo, m.roughness, m.ior, m.clearcoat, m.clearcoatRoughness, m.metalicity, m.transmission)
+		if m.conductor != nil {
+			description += fmt.Sprintf(", conductor %v", *m.conductor)
+		}
+		if m.absorption != (Color{0, 0, 0}) || m.priority != 0 {
+			description += fmt.Sprintf(", absorption %v (priority %d)", m.absorption, m.priority)
+		}
+		if m.anisotropic != 0 {
+			description += fmt.Sprintf(", anisotropic %g (rotation %g)", m.anisotropic, m.anisotropicRotation)
+		}
+		if m.sigma != 0 {
+			description += fmt.Sprintf(", oren-nayar sigma %g", m.sigma)
+		}
+		if m.film.thickness != 0 {
+			description += fmt.Sprintf(", thin film %v", m.film)
+		}
+		if m.maps != nil {
+			description += ", maps " + m.maps.String()
+		}
+		return description
+	case Principled:
+		return "principled, " + m.principled.String()
+	case Subsurface:
+		return fmt.Sprintf("subsurface, albedo %v, radius %v, roughness %g, ior %g", m.albedo, m.radius, m.roughness, m.ior)
+	case Mix:
+		return "mix, " + m.mix.String()
+	case Layered:
+		return "layered, " + m.layer.String()
+	}
+	return "unknown"
+}
+
+// ScatterRecord describes a ray scattered by a material
+type ScatterRecord struct {
+	ray Ray
+	// attenuation is the BSDF times the cosine divided by pdf
+	attenuation Color
+	// pdf is the probability density of the direction in solid angle, specular is set instead
+	// if the direction was sampled from a perfectly smooth lobe, which can't be evaluated
+	pdf      float64
+	specular bool
+	// subsurface is set if the ray goes into a subsurface material, light scatters inside
+	// and leaves it elsewhere, see randomWalk
+	subsurface bool
+}
+
+// clearcoatIOR is the index of refraction of the clearcoat layer
+const clearcoatIOR = 1.5
+
+// bsdfLobes are probabilities of sampling lobes of a BSDF material
+type bsdfLobes struct {
+	clearcoat, metal, transmission, specular, diffuse float64
+}
+
+// lobes returns probabilities of sampling lobes for light leaving at cosine cosO with the normal.
+// Light not reflected by the clearcoat reaches the layers below it, so the probability of the
+// clearcoat is its Fresnel reflectance, likewise for the specular layer over the diffuse base
+func (m Material) lobes(cosO float64) bsdfLobes {
+	var l bsdfLobes
+	l.clearcoat = m.coat(cosO)
+	base := 1 - l.clearcoat
+	l.metal = base * m.metalicity
+	l.transmission = base * (1 - m.metalicity) * m.transmission
+	opaque := base * (1 - m.metalicity) * (1 - m.transmission)
+	l.specular = opaque * m.film.dielectric(cosO, m.ior).mean()
+	l.diffuse = opaque - l.specular
+	return l
+}
+
+// coat returns the fraction of light reflected by the clearcoat at cosine cos with the normal
+func (m Material) coat(cos float64) float64 {
+	return m.clearcoat * frDielectric(math.Abs(cos), clearcoatIOR)
+}
+
+// metalFresnel returns reflectance of the metal lobe for light arriving at cosine cos with the microfacet
+func (m Material) metalFresnel(albedo Color, cos float64) Color {
+	if m.conductor != nil {
+		return albedo.Mul(m.film.conductor(cos, *m.conductor))
+	}
+	if m.film.thickness > 0 {
+		// interference needs the index of refraction of the metal, albedo is its reflectivity
+		return m.film.conductor(cos, EdgeTint(albedo, Color{1, 1, 1}))
+	}
+	return schlickColor(albedo, cos)
+}
+
+// frame returns the shading frame at the hit, the tangent is rotated for anisotropic materials
+func (m Material) frame(rec HitRecord) ONB {
+	frame := rec.frame()
+	rotation := m.anisotropicRotation
+	if m.principled != nil {
+		rotation = m.principled.AnisotropicRotation.value(rec)
+	}
+	if rotation != 0 {
+		sin, cos := math.Sincos(2 * math.Pi * rotation)
+		frame.u = frame.u.MulScalar(cos).Add(frame.v.MulScalar(sin))
+		frame.v = frame.w.Cross(frame.u)
+	}
+	return frame
+}
+
+// Eval returns the BSDF multiplied by the cosine between wi and the normal, for light arriving
+// from wi and leaving in wo, both point away from the surface. Perfectly smooth lobes aren't included
+func (m Material) Eval(rec HitRecord, wo, wi Tuple) Color {
+	m = m.pick(rec, wo).at(rec)
+	switch m.material {
+	case Subsurface:
+		return m.surface().Eval(rec, wo, wi)
+	case Layered:
+		return m.layer.eval(rec, wo, wi)
+	}
+	frame := m.frame(rec)
+	wo, wi = frame.coordinates(wo), frame.coordinates(wi)
+	albedo := m.albedo.color(rec)
+	switch m.material {
+	case Lambertian:
+		if wo.z*wi.z <= 0 {
+			return Color{0, 0, 0}
+		}
+		return albedo.MulScalar(orenNayar(m.sigma, wo, wi))
+	case BSDF:
+		return m.evalBSDF(albedo, wo, wi)
+	case Principled:
+		return evalPrincipled(m.principled.at(rec), wo, wi)
+	}
+	return Color{0, 0, 0}
+}
+
+// evalBSDF evaluates the layers of a BSDF material in the local shading frame. The clearcoat
+// is on top, light passing through it in both directions reaches either a conductor, a rough
+// dielectric which transmits light or an opaque dielectric with a diffuse base
+func (m Material) evalBSDF(albedo Color, wo, wi Tuple) Color {
+	d := anisotropicGGX(m.roughness, m.anisotropic)
+	base := (1 - m.coat(wo.z)) * (1 - m.coat(wi.z))
+	f := Color{0, 0, 0}
+
+	// transmission is the only lobe which tells the sides of the surface apart
+	if t := base * (1 - m.metalicity) * m.transmission; t > 0 {
+		reflection, transmission := roughDielectric(d, wo, wi, m.ior, m.film)
+		f = albedo.sqrt().Mul(transmission).Add(reflection).MulScalar(t)
+	}
+
+	// the rest reflects light on both sides
+	if wo.z < 0 {
+		wo.z, wi.z = -wo.z, -wi.z
+	}
+	if wi.z <= 0 {
+		return f
+	}
+	h := wo.Add(wi).Normalize()
+	if m.clearcoat > 0 {
+		c := m.clearcoat * frDielectric(wo.Dot(h), clearcoatIOR) * microfacetReflection(isotropic(ggxAlpha(m.clearcoatRoughness)), wo, wi)
+		f = f.Add(Color{c, c, c})
+	}
+	if metal := base * m.metalicity; metal > 0 {
+		f = f.Add(m.metalFresnel(albedo, wo.Dot(h)).MulScalar(metal * microfacetReflection(d, wo, wi)))
+	}
+	if opaque := base * (1 - m.metalicity) * (1 - m.transmission); opaque > 0 {
+		specular := m.film.dielectric(wo.Dot(h), m.ior).MulScalar(microfacetReflection(d, wo, wi))
+		// light reaching the diffuse base is refracted through the specular layer twice
+		white := Color{1, 1, 1}
+		entering := white.Subtract(m.film.dielectric(wo.z, m.ior)).Mul(white.Subtract(m.film.dielectric(wi.z, m.ior)))
+		diffuse := albedo.Mul(entering).MulScalar(orenNayar(m.sigma, wo, wi))
+		f = f.Add(diffuse.Add(specular).MulScalar(opaque))
+	}
+	return f
+}
+
+// Pdf returns the probability density of Scatter sampling wi for wo in solid angle,
+// perfectly smooth lobes aren't included
+func (m Material) Pdf(rec HitRecord, wo, wi Tuple) float64 {
+	m = m.pick(rec, wo).at(rec)
+	switch m.material {
+	case Subsurface:
+		return m.surface().Pdf(rec, wo, wi)
+	case Layered:
+		return m.layer.pdf(rec, wo, wi)
+	}
+	frame := m.frame(rec)
+	wo, wi = frame.coordinates(wo), frame.coordinates(wi)
+	switch m.material {
+	case Lambertian:
+		if wo.z*wi.z <= 0 {
+			return 0
+		}
+		return math.Abs(wi.z) / math.Pi
+	case BSDF:
+		d := anisotropicGGX(m.roughness, m.anisotropic)
+		l := m.lobes(math.Abs(wo.z))
+		pdf := 0.0
+		if l.transmission > 0 {
+			pdf = l.transmission * roughDielectricPdf(d, wo, wi, m.ior, m.film)
+		}
+		if wo.z < 0 {
+			wo.z, wi.z = -wo.z, -wi.z
+		}
+		if wi.z <= 0 {
+			return pdf
+		}
+		pdf += l.clearcoat * microfacetReflectionPdf(isotropic(ggxAlpha(m.clearcoatRoughness)), wo, wi)
+		pdf += (l.metal + l.specular) * microfacetReflectionPdf(d, wo, wi)
+		return pdf + l.diffuse*wi.z/math.Pi
+	case Principled:
+		return pdfPrincipled(m.principled.at(rec), wo, wi)
+	}
+	return 0
+}
+
+// Scatter samples a ray scattered at the hit point, it returns false if the ray is absorbed.
+// Emission materials don't scatter light
+func (m Material) Scatter(r Ray, rec HitRecord, sampler Sampler, srec *ScatterRecord) bool {
+	m = m.pick(rec, r.direction.Normalize().Negate()).at(rec)
+	srec.subsurface = false
+	switch m.material {
+	case Subsurface:
+		if !m.surface().Scatter(r, rec, sampler, srec) {
+			return false
+		}
+		// both light refracted in and light reflected back inside scatter in the material
+		srec.subsurface = srec.ray.direction.Dot(rec.normal) < 0
+		return true
+	case Layered:
+		return m.scatterLayered(r, rec, sampler, srec)
+	}
+	frame := m.frame(rec)
+	wo := frame.coordinates(r.direction.Normalize().Negate())
+	// reflection lobes are sampled above the surface and mirrored to the side of wo
+	side := 1.0
+	if wo.z < 0 {
+		side = -1
+	}
+	above := Tuple{wo.x, wo.y, wo.z * side, 0}
+	mirror := Tuple{-wo.x, -wo.y, wo.z, 0}
+	srec.specular = false
+
+	// light reflected by smooth layers below the clearcoat passes through it towards wi
+	below := func(wi Tuple, tint Color) bool {
+		return m.scatterSpecular(rec, frame, wi, tint.MulScalar(1-m.coat(wi.z)), srec)
+	}
+
+	var wi Tuple
+	switch m.material {
+	case Lambertian:
+		wi = Tuple{0, 0, 1, 0}.Add(sampleSphere(sampler.Get2D())).Normalize()
+	case BSDF:
+		l := m.lobes(above.z)
+		lobe := sampler.Get1D()
+		u, v := sampler.Get2D()
+		d := anisotropicGGX(m.roughness, m.anisotropic)
+		switch {
+		case lobe < l.clearcoat:
+			coat := isotropic(ggxAlpha(m.clearcoatRoughness))
+			if coat.smooth() {
+				// the probability of the lobe is its reflectance
+				return m.scatterSpecular(rec, frame, mirror, Color{1, 1, 1}, srec)
+			}
+			wi = reflect(above, coat.sample(above, u, v))
+		case lobe < l.clearcoat+l.metal:
+			if d.smooth() {
+				return below(mirror, m.metalFresnel(m.albedo.color(rec), above.z))
+			}
+			wi = reflect(above, d.sample(above, u, v))
+		case lobe < l.clearcoat+l.metal+l.transmission:
+			// transmission distinguishes sides of the surface, so it's sampled for wo as it is
+			refracted, weight, reflected, ok := sampleDielectric(d, wo, m.ior, m.film, u, v, sampler.Get1D())
+			switch {
+			case !ok:
+				return false
+			case !d.smooth():
+				return m.scatterSampled(rec, frame, wo, refracted, srec)
+			case reflected:
+				return below(refracted, weight)
+			}
+			return below(refracted, m.albedo.color(rec).sqrt().Mul(weight))
+		case lobe < l.clearcoat+l.metal+l.transmission+l.specular:
+			if d.smooth() {
+				// the lobe is chosen with the mean reflectance of the channels
+				fresnel := m.film.dielectric(above.z, m.ior)
+				return below(mirror, fresnel.DivScalar(fresnel.mean()))
+			}
+			wi = reflect(above, d.sample(above, u, v))
+		default:
+			wi = Tuple{0, 0, 1, 0}.Add(sampleSphere(u, v)).Normalize()
+		}
+	case Principled:
+		return m.scatterPrincipled(rec, frame, wo, sampler, srec)
+	default:
+		return false
+	}
+	wi.z *= side
+	return m.scatterSampled(rec, frame, wo, wi, srec)
+}
+
+// scatterSampled scatters the ray to wi in the local frame sampled from lobes which can be evaluated
+func (m Material) scatterSampled(rec HitRecord, frame ONB, wo, wi Tuple, srec *ScatterRecord) bool {
+	wo, wi = frame.local(wo), frame.local(wi)
+	srec.pdf = m.Pdf(rec, wo, wi)
+	if srec.pdf <= 0 {
+		return false
+	}
+	srec.ray = Ray{rec.p, wi}
+	srec.attenuation = m.Eval(rec, wo, wi).DivScalar(srec.pdf)
+	return true
+}
+
+// scatterSpecular scatters the ray to wi in the local frame sampled from a perfectly smooth lobe,
+// lobes are chosen in proportion to their reflectance, so only the tint of the lobe is left
+func (m Material) scatterSpecular(rec HitRecord, frame ONB, wi Tuple, attenuation Color, srec *ScatterRecord) bool {
+	srec.ray = Ray{rec.p, frame.local(wi)}
+	srec.attenuation = attenuation
+	srec.pdf, srec.specular = 0, true
+	return true
+}
@@ -0,0 +1,202 @@
+package pt
+
+import (
+	"math"
+	"testing"
+)
+
+// albedo estimates the fraction of light arriving from all directions reflected and transmitted
+// towards wo, which is at most one for white materials
+func albedo(m Material, normal, wo Tuple, samples int) Color {
+	rec := HitRecord{p: Tuple{0, 0, 0, 0}, normal: normal, material: m}
+	r := Ray{wo, wo.Negate()}
+	sampler, _ := NewSampler("sobol", samples, 0)
+	sum := Color{0, 0, 0}
+	for i := 0; i < samples; i++ {
+		sampler.StartPixelSample(0, i)
+		var srec ScatterRecord
+		if m.Scatter(r, rec, sampler, &srec) {
+			sum = sum.Add(srec.attenuation)
+		}
+	}
+	return sum.DivScalar(float64(samples))
+}
+
+// TestWhiteFurnace checks that white materials don't create energy and that lossless ones keep
+// most of it, rough microfacets lose some energy to light scattered between them
+func TestWhiteFurnace(t *testing.T) {
+	white := NewConstant(Color{1, 1, 1})
+	normal := Tuple{0, 0, 1, 0}
+	tests := []struct {
+		name     string
+		material Material
+		min      float64
+	}{
+		{"lambertian", NewLambertian(white), 0.99},
+		{"oren-nayar", NewLambertian(white).WithOrenNayar(30), 0.75},
+		{"rough plastic", NewGlossy(white, 0.3, 0).WithOrenNayar(20), 0.75},
+		{"smooth metal", NewMetal(white, 0, 0, 0), 0.999},
+		{"rough metal", NewMetal(white, 0.3, 0, 0), 0.9},
+		{"very rough metal", NewMetal(white, 1, 0, 0), 0.25},
+		{"smooth glass", NewDielectric(white, 0, 0, 1.5), 0.999},
+		{"rough glass", NewDielectric(white, 0.3, 0, 1.5), 0.9},
+		{"very rough glass", NewDielectric(white, 1, 0, 1.5), 0.35},
+		{"plastic", NewGlossy(white, 0.3, 0), 0.8},
+		{"clearcoat", NewGlossy(white, 0.5, 1), 0.6},
+		{"coated metal", NewMetal(white, 0.2, 1, 0.05), 0.8},
+		{"silver", NewConductor(conductors["silver"], 0.3, 0, 0), 0.85},
+		{"soap bubble", NewDielectric(white, 0, 0, 1).WithThinFilm(350, 1.33), 0.999},
+		{"oily glass", NewDielectric(white, 0.2, 0, 1.5).WithThinFilm(400, 1.47), 0.9},
+		{"anodized metal", NewMetal(white, 0.2, 0, 0).WithThinFilm(250, 2), 0.9},
+		{"iridescent plastic", NewGlossy(white, 0.3, 0).WithThinFilm(500, 1.4), 0.8},
+		{"brushed metal", NewMetal(white, 0.3, 0, 0).WithAnisotropy(0.8, 0.1), 0.85},
+		{"mixture", NewBSDF(white, 0.4, 1.5, 0.5, 0.1, 0.3, 0.5), 0.7},
+		{"mix", NewMix(NewLambertian(white), NewDielectric(white, 0, 0, 1.5), gray(0.5)), 0.99},
+		{"layered", NewLayered(NewMetal(white, 0.4, 0, 0), 0.1, 1.5, 0, Color{0, 0, 0}), 0.75},
+		{"coated glass", NewLayered(NewDielectric(white, 0.2, 0, 1.5), 0, 1.3, 0, Color{0, 0, 0}), 0.8},
+		{"principled metal", principledMaterial(func(p *PrincipledParams) {
+			p.BaseColor, p.Metallic, p.Roughness, p.Anisotropic = white, gray(1), gray(0.3), gray(0.5)
+		}), 0.9},
+		{"principled glass", principledMaterial(func(p *PrincipledParams) {
+			p.BaseColor, p.SpecTrans, p.Roughness = white, gray(1), gray(0)
+		}), 0.999},
+		{"principled thin glass", principledMaterial(func(p *PrincipledParams) {
+			p.BaseColor, p.SpecTrans, p.Roughness, p.Thin = white, gray(1), gray(0), gray(1)
+		}), 0.999},
+		{"principled rough thin glass", principledMaterial(func(p *PrincipledParams) {
+			p.BaseColor, p.SpecTrans, p.Roughness, p.Thin = white, gray(1), gray(0.3), gray(1)
+		}), 0.9},
+	}
+	for _, test := range tests {
+		for _, cos := range []float64{1, 0.7, 0.3, 0.05} {
+			wo := Tuple{math.Sqrt(1 - cos*cos), 0, cos, 0}
+			for _, side := range []float64{1, -1} {
+				a := albedo(test.material, normal.MulScalar(side), wo, 1<<14)
+				for _, c := range []float64{a.r, a.g, a.b} {
+					if c > 1.005 {
+						t.Errorf("%s at cosine %g (side %g) reflects %g of light", test.name, cos, side, c)
+					}
+					if cos >= 0.3 && c < test.min {
+						t.Errorf("%s at cosine %g (side %g) reflects %g of light, expected at least %g", test.name, cos, side, c, test.min)
+					}
+				}
+			}
+		}
+	}
+}
+
+// principledMaterial returns a principled material with parameters changed by set
+func principledMaterial(set func(p *PrincipledParams)) Material {
+	p := DefaultPrincipled()
+	set(&p)
+	return NewPrincipled(p)
+}
+
+// TestReciprocity checks that reflection doesn't change when directions are swapped
+func TestReciprocity(t *testing.T) {
+	rec := HitRecord{p: Tuple{0, 0, 0, 0}, normal: Tuple{0, 0, 1, 0}, tangent: Tuple{1, 1, 0, 0}}
+	rng := NewRNG(1, 0)
+	equal := func(a, b float64) bool {
+		return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+	}
+	for _, m := range []Material{
+		NewLambertian(NewConstant(Color{0.8, 0.5, 0.2})),
+		NewGlossy(NewConstant(Color{0.8, 0.5, 0.2}), 0.3, 1),
+		NewLambertian(NewConstant(Color{0.8, 0.5, 0.2})).WithOrenNayar(25),
+		NewGlossy(NewConstant(Color{0.8, 0.5, 0.2}), 0.3, 0).WithOrenNayar(40),
+		NewMetal(NewConstant(Color{0.9, 0.6, 0.3}), 0.4, 0.5, 0.1),
+		NewDielectric(NewConstant(Color{1, 1, 1}), 0.3, 0, 1.5),
+		NewMetal(NewConstant(Color{0.9, 0.6, 0.3}), 0.4, 0, 0).WithAnisotropy(0.9, 0.3),
+		NewGlossy(NewConstant(Color{0.8, 0.5, 0.2}), 0.3, 0).WithThinFilm(450, 1.33),
+		NewDielectric(NewConstant(Color{1, 1, 1}), 0.3, 0, 1.5).WithThinFilm(300, 1.38),
+		NewConductor(conductors["titanium"], 0.3, 0, 0).WithThinFilm(200, 2.4),
+		NewBSDF(NewConstant(Color{0.3, 0.8, 0.3}), 0.4, 1.5, 0.5, 0.1, 0.3, 0.5),
+		NewLayered(NewLambertian(NewConstant(Color{0.8, 0.5, 0.2})), 0.2, 1.6, 0.1, Color{0.5, 1, 2}),
+		principledMaterial(func(p *PrincipledParams) {
+			p.Subsurface, p.Sheen, p.Clearcoat, p.Anisotropic, p.SpecTrans = gray(0.5), gray(1), gray(1), gray(0.7), gray(0.3)
+		}),
+		principledMaterial(func(p *PrincipledParams) {
+			p.Thin, p.Flatness, p.DiffTrans, p.Sheen, p.SpecTrans = gray(1), gray(0.5), gray(0.4), gray(1), gray(0.3)
+		}),
+	} {
+		rec.material = m
+		for i := 0; i < 100; i++ {
+			wo := sampleSphere(RandFloat(rng), RandFloat(rng))
+			wi := sampleSphere(RandFloat(rng), RandFloat(rng))
+			wo.z, wi.z = math.Abs(wo.z), math.Abs(wi.z)
+			f := m.Eval(rec, wo, wi).DivScalar(wi.z)
+			g := m.Eval(rec, wi, wo).DivScalar(wo.z)
+			if !equal(f.r, g.r) || !equal(f.g, g.g) || !equal(f.b, g.b) {
+				t.Fatalf("%v: BSDF %v for %v and %v, %v when swapped", m, f, wo, wi, g)
+			}
+		}
+	}
+}
+
+// TestAnisotropyRotation checks that rotating the tangent of a surface by a quarter of a turn
+// is the same as rotating the anisotropy of the material
+func TestAnisotropyRotation(t *testing.T) {
+	metal := NewMetal(NewConstant(Color{0.9, 0.9, 0.9}), 0.4, 0, 0)
+	rotated := HitRecord{normal: Tuple{0, 0, 1, 0}, tangent: Tuple{0, 1, 0, 0}, material: metal.WithAnisotropy(0.9, 0)}
+	rec := HitRecord{normal: Tuple{0, 0, 1, 0}, tangent: Tuple{1, 0, 0, 0}, material: metal.WithAnisotropy(0.9, 0.25)}
+	wo := Tuple{0.6, 0, 0.8, 0}
+	for _, wi := range []Tuple{{-0.6, 0, 0.8, 0}, {-0.5, 0.3, 0.8, 0}, {0, 0.6, 0.8, 0}} {
+		wi = wi.Normalize()
+		f, g := rec.material.Eval(rec, wo, wi), rotated.material.Eval(rotated, wo, wi)
+		if !Equal(f.r, g.r) || !Equal(f.g, g.g) || !Equal(f.b, g.b) {
+			t.Errorf("BSDF %v for %v with rotated anisotropy, %v with rotated tangent", f, wi, g)
+		}
+	}
+	isotropic := HitRecord{normal: Tuple{0, 0, 1, 0}, tangent: Tuple{1, 0, 0, 0}, material: metal}
+	wi := Tuple{-0.6, 0, 0.8, 0}
+	if f, g := rec.material.Eval(rec, wo, wi), isotropic.material.Eval(isotropic, wo, wi); Equal(f.r, g.r) {
+		t.Errorf("anisotropic BSDF %v is the same as isotropic", f)
+	}
+}
+
+// TestMaterialMaps checks that textured parameters are the same as constant ones at every point
+func TestMaterialMaps(t *testing.T) {
+	white := NewConstant(Color{1, 1, 1})
+	roughness := NewCheckerboard(Color{0.1, 0.1, 0.1}, Color{0.7, 0.7, 0.7}, 1, 1, 1)
+	metalicity := gray(1)
+	textured := NewBSDF(white, 0, 1.5, 0, 0, 0, 0).
+		WithMaps(MaterialMaps{Roughness: &roughness}).
+		WithMaps(MaterialMaps{Metalicity: &metalicity})
+	wo, wi := Tuple{0.6, 0, 0.8, 0}, Tuple{-0.5, 0.1, 0.8, 0}.Normalize()
+	for _, test := range []struct {
+		p        Tuple
+		constant Material
+	}{
+		{Tuple{0.5, 0.5, 0.5, 1}, NewBSDF(white, 0.1, 1.5, 0, 0, 1, 0)},
+		{Tuple{1.5, 0.5, 0.5, 1}, NewBSDF(white, 0.7, 1.5, 0, 0, 1, 0)},
+	} {
+		rec := HitRecord{p: test.p, normal: Tuple{0, 0, 1, 0}, tangent: Tuple{1, 0, 0, 0}}
+		f, g := textured.Eval(rec, wo, wi), test.constant.Eval(rec, wo, wi)
+		if !Equal(f.r, g.r) || !Equal(f.g, g.g) || !Equal(f.b, g.b) {
+			t.Errorf("BSDF %v at %v with maps, %v with constant parameters", f, test.p, g)
+		}
+		if p, q := textured.Pdf(rec, wo, wi), test.constant.Pdf(rec, wo, wi); !Equal(p, q) {
+			t.Errorf("pdf %g at %v with maps, %g with constant parameters", p, test.p, q)
+		}
+	}
+}
+
+// TestOrenNayar checks that rough diffuse reflection is Lambertian without roughness and that it
+// reflects more light back towards its source than forwards
+func TestOrenNayar(t *testing.T) {
+	rng := NewRNG(1, 0)
+	for i := 0; i < 100; i++ {
+		wo := sampleSphere(RandFloat(rng), RandFloat(rng))
+		wi := sampleSphere(RandFloat(rng), RandFloat(rng))
+		wo.z, wi.z = math.Abs(wo.z), math.Abs(wi.z)
+		if f := orenNayar(0, wo, wi); !Equal(f, wi.z/math.Pi) {
+			t.Fatalf("got %g for %v and %v without roughness, expected %g", f, wo, wi, wi.z/math.Pi)
+		}
+	}
+	wo := Tuple{0.8, 0, 0.6, 0}
+	// the values include the cosine with the normal, divided out to compare them
+	back, forward := orenNayar(30, wo, wo)/0.6, orenNayar(30, wo, Tuple{-0.6, 0, 0.8, 0})/0.8
+	if lambert := 1 / math.Pi; back <= lambert || forward >= lambert {
+		t.Errorf("got %g towards the light and %g away from it, Lambertian reflection is %g", back, forward, lambert)
+	}
+}
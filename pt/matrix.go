@@ -1,4 +1,4 @@
-package main
+package pt
 
 import (
 	"math"
@@ -158,9 +158,7 @@ func (mat Mat) Minor(row, column int) float64 {
 // Cofactor returns cofactor of 3x3 matrix
 func (mat Mat) Cofactor(row, column int) float64 {
 	minor := mat.Minor(row, column)
-	row++
-	column++
-	if row*column%2 == 0 {
+	if (row+column)%2 == 1 {
 		return -minor
 	}
 	return minor
@@ -180,10 +178,11 @@ func (mat Mat) Invert() Mat {
 	originalShape := mat.MatShape()
 	returnMat := Mat{tempArray}
 
+	determinant := mat.Determinant()
 	for i := 0; i < originalShape[0]; i++ {
 		for j := 0; j < originalShape[1]; j++ {
-			c := mat.Cofactor(i, j)
-			tempArray[i][j] = c / mat.Determinant()
+			// inverse is the transposed matrix of cofactors divided by determinant
+			tempArray[j][i] = mat.Cofactor(i, j) / determinant
 		}
 	}
 
@@ -0,0 +1,55 @@
+package pt
+
+import "testing"
+
+func TestMatInvert(t *testing.T) {
+	tests := []Mat{
+		TranslationMat(1, 2, -3)[0],
+		ScaleMat(2, 3, 4)[0],
+		RotateXMat(0.3)[0],
+		TranslationMat(1, 2, -3)[0].MatMul(RotateYMat(0.7)[0]).MatMul(ScaleMat(2, 0.5, 4)[0]),
+		{[][]float64{
+			{8, -5, 9, 2},
+			{7, 5, 6, 1},
+			{-6, 0, 9, 6},
+			{-3, 0, -9, -4},
+		}},
+	}
+	for i, m := range tests {
+		product := m.MatMul(m.Invert())
+		identity := GetIdentityMatrix(4)
+		for row := 0; row < 4; row++ {
+			for column := 0; column < 4; column++ {
+				if !Equal(product.mat[row][column], identity.mat[row][column]) {
+					t.Fatalf("matrix %d: product with its inverse isn't identity: %v", i, product.mat)
+				}
+			}
+		}
+	}
+}
+
+func TestMatInvertKnown(t *testing.T) {
+	m := Mat{[][]float64{
+		{-5, 2, 6, -8},
+		{1, -5, 1, 8},
+		{7, 7, -6, -7},
+		{1, -3, 7, 4},
+	}}
+	expected := [][]float64{
+		{0.21805, 0.45113, 0.24060, -0.04511},
+		{-0.80827, -1.45677, -0.44361, 0.52068},
+		{-0.07895, -0.22368, -0.05263, 0.19737},
+		{-0.52256, -0.81391, -0.30075, 0.30639},
+	}
+	if d := m.Determinant(); !Equal(d, 532) {
+		t.Errorf("got determinant %v, expected 532", d)
+	}
+	inverse := m.Invert()
+	for row := range expected {
+		for column := range expected[row] {
+			if diff := inverse.mat[row][column] - expected[row][column]; diff > 1e-5 || diff < -1e-5 {
+				t.Fatalf("got inverse %v, expected %v", inverse.mat, expected)
+			}
+		}
+	}
+}
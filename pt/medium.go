@@ -0,0 +1,110 @@
+package pt
+
+import "math"
+
+// medium is the inside of a dielectric, light travelling through it is absorbed according to
+// the Beer-Lambert law
+type medium struct {
+	ior        float64
+	absorption Color
+	priority   int
+}
+
+// vacuum surrounds all dielectrics
+var vacuum = medium{ior: 1}
+
+// transmittance returns the fraction of light left after travelling distance through the medium
+func (m medium) transmittance(distance float64) Color {
+	if m.absorption == (Color{0, 0, 0}) {
+		return Color{1, 1, 1}
+	}
+	return Color{
+		math.Exp(-m.absorption.r * distance),
+		math.Exp(-m.absorption.g * distance),
+		math.Exp(-m.absorption.b * distance),
+	}
+}
+
+// media are dielectrics containing a point of a path in the order they were entered. Where they
+// overlap, the one with the highest priority fills the space and surfaces of the others inside it
+// are ignored (Schmidt and Budge 2002), so liquid in a glass can overlap its walls
+type media []medium
+
+// current returns the medium the path is in, the last entered one with the highest priority
+func (s media) current() medium {
+	current := vacuum
+	found := false
+	for _, m := range s {
+		if !found || m.priority >= current.priority {
+			current, found = m, true
+		}
+	}
+	return current
+}
+
+// enter returns the media after entering m
+func (s media) enter(m medium) media {
+	entered := make(media, len(s), len(s)+1)
+	copy(entered, s)
+	return append(entered, m)
+}
+
+// exit returns the media after leaving the last entered m. Media are matched without their index
+// of refraction, which can be textured and differ between the points where a dielectric is entered
+// and left
+func (s media) exit(m medium) media {
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i].absorption == m.absorption && s[i].priority == m.priority {
+			left := make(media, 0, len(s)-1)
+			left = append(left, s[:i]...)
+			return append(left, s[i+1:]...)
+		}
+	}
+	return s
+}
+
+// cross returns media behind the surface of a dielectric hit by the ray and the medium outside the
+// dielectric. The surface is ignored if the dielectric is inside one with higher priority
+func (s media) cross(r Ray, rec HitRecord) (back media, outer medium, ignored bool) {
+	m := rec.material.medium()
+	if r.direction.Dot(rec.normal) < 0 {
+		back, outer = s.enter(m), s.current()
+	} else {
+		back = s.exit(m)
+		outer = back.current()
+	}
+	return back, outer, m.priority < outer.priority
+}
+
+// WithAbsorption returns the dielectric with light absorbed inside it, absorption is the
+// coefficient per unit of distance for every channel
+func (m Material) WithAbsorption(absorption Color) Material {
+	m.absorption = absorption
+	return m
+}
+
+// WithTransmittance returns the dielectric absorbing light inside it, so that color is left
+// of white light after travelling distance
+func (m Material) WithTransmittance(color Color, distance float64) Material {
+	coefficient := func(c float64) float64 {
+		return -math.Log(math.Max(1e-6, math.Min(1, c))) / distance
+	}
+	return m.WithAbsorption(Color{coefficient(color.r), coefficient(color.g), coefficient(color.b)})
+}
+
+// WithPriority returns the dielectric with priority of its medium, where dielectrics overlap the
+// one with the highest priority is used, 0 by default
+func (m Material) WithPriority(priority int) Material {
+	m.priority = priority
+	return m
+}
+
+// dielectric checks if the material refracts light, so it has a medium inside
+func (m Material) dielectric() bool {
+	return m.material == BSDF && m.transmission > 0 && m.metalicity < 1
+}
+
+// medium returns the inside of a dielectric
+func (m Material) medium() medium {
+	return medium{m.ior, m.absorption, m.priority}
+}
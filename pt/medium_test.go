@@ -0,0 +1,97 @@
+package pt
+
+import (
+	"io"
+	"log"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestMedia(t *testing.T) {
+	glass := medium{1.5, Color{0, 0, 0}, 1}
+	water := medium{1.33, Color{0.1, 0.1, 0.1}, 0}
+	var inside media
+	if inside.current() != vacuum {
+		t.Errorf("got %v outside of dielectrics, expected vacuum", inside.current())
+	}
+	inside = inside.enter(glass)
+	inWater := inside.enter(water)
+	if inWater.current() != glass {
+		t.Errorf("got %v in water overlapping glass, expected glass with higher priority", inWater.current())
+	}
+	if len(inside) != 1 {
+		t.Errorf("entering changed the original media %v", inside)
+	}
+	if left := inWater.exit(glass); len(left) != 1 || left.current() != water {
+		t.Errorf("got %v after leaving glass, expected water", left)
+	}
+	if left := inWater.exit(medium{2, Color{}, 2}); len(left) != 2 {
+		t.Errorf("leaving a medium which wasn't entered changed media to %v", left)
+	}
+	if left := inWater.exit(medium{1.2, water.absorption, water.priority}); len(left) != 1 || left.current() != glass {
+		t.Errorf("got %v after leaving water with a different index of refraction, expected glass", left)
+	}
+}
+
+func TestTransmittance(t *testing.T) {
+	color := Color{0.8, 0.5, 0.1}
+	m := NewDielectric(NewConstant(Color{1, 1, 1}), 0, 0, 1.5).WithTransmittance(color, 2).medium()
+	tr := m.transmittance(2)
+	if !Equal(tr.r, color.r) || !Equal(tr.g, color.g) || !Equal(tr.b, color.b) {
+		t.Errorf("got transmittance %v after the reference distance, expected %v", tr, color)
+	}
+	if tr := m.transmittance(4); !Equal(tr.g, color.g*color.g) {
+		t.Errorf("got transmittance %v after twice the reference distance, expected %v", tr, color.g*color.g)
+	}
+}
+
+// TestAbsorption checks that light is absorbed along paths through nested dielectrics, which don't
+// refract or reflect light with index of refraction 1
+func TestAbsorption(t *testing.T) {
+	clear := func(absorption float64, priority int) Material {
+		return NewDielectric(NewConstant(Color{1, 1, 1}), 0, 0, 1).WithAbsorption(Color{absorption, absorption, absorption}).WithPriority(priority)
+	}
+	tests := []struct {
+		name     string
+		outer    Material
+		inner    Material
+		expected float64
+	}{
+		{"same medium", clear(0.5, 0), clear(0.5, 0), math.Exp(-0.5 * 2)},
+		{"inner has higher priority", clear(0.5, 0), clear(2, 1), math.Exp(-0.5*1 - 2*1)},
+		{"inner has lower priority", clear(0.5, 1), clear(2, 0), math.Exp(-0.5 * 2)},
+		{"same priority", clear(0.5, 0), clear(2, 0), math.Exp(-0.5*1 - 2*1)},
+	}
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	opts := Options{Depth: 10}
+	sampler, _ := NewSampler("independent", 1, 0)
+	for _, test := range tests {
+		scene := NewScene()
+		scene.AddSphere(Tuple{0, 0, 0, 0}, 1, test.outer)
+		scene.AddSphere(Tuple{0, 0, 0, 0}, 0.5, test.inner)
+		scene.SetEnvironment(NewConstant(Color{1, 1, 1}))
+		scene.buildWorld()
+		sampler.StartPixelSample(0, 0)
+		c := colorize(Ray{Tuple{0, 0, -5, 0}, Tuple{0, 0, 1, 0}}, scene, &opts, 0, 0, sampler, nil)
+		if math.Abs(c.r-test.expected) > 1e-9 {
+			t.Errorf("%s: got %v, expected %v", test.name, c.r, test.expected)
+		}
+	}
+
+	// a textured index of refraction differs where the ray enters and leaves the sphere, light
+	// reaching a light behind it must only be absorbed inside
+	ior := NewCheckerboard(Color{1, 1, 1}, Color{1.0001, 1.0001, 1.0001}, 1000, 1000, 2)
+	scene := NewScene()
+	scene.AddSphere(Tuple{0, 0, 0, 0}, 1, clear(0.5, 0).WithMaps(MaterialMaps{IOR: &ior}))
+	scene.AddSphere(Tuple{0, 0, 10, 0}, 5, NewEmission(NewConstant(Color{1, 1, 1})))
+	scene.buildWorld()
+	sampler.StartPixelSample(0, 0)
+	r := Ray{Tuple{0.1, 0.1, -5, 0}, Tuple{0, 0, 1, 0}}
+	c := colorize(r, scene, &opts, 0, 0, sampler, nil)
+	if expected := math.Exp(-0.5 * 2 * math.Sqrt(0.98)); math.Abs(c.r-expected) > 1e-4 {
+		t.Errorf("textured ior: got %v, expected %v", c.r, expected)
+	}
+}
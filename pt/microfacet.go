@@ -0,0 +1,333 @@
+package pt
+
+import "math"
+
+// smoothAlpha is the largest alpha of distributions treated as perfectly smooth,
+// they reflect and refract in a single direction
+const smoothAlpha = 1e-3
+
+// ggxAlpha converts perceptual roughness to the alpha parameter of GGX
+func ggxAlpha(roughness float64) float64 {
+	return roughness * roughness
+}
+
+// microfacet is the GGX distribution of microfacet normals with Smith shadowing-masking,
+// in the local shading frame where z is the surface normal
+type microfacet struct {
+	alphaX, alphaY float64
+}
+
+func isotropic(alpha float64) microfacet {
+	return microfacet{alpha, alpha}
+}
+
+// anisotropicGGX returns the distribution for perceptual roughness, anisotropic from 0 to 1
+// makes it rougher along the tangent (x) and smoother along the bitangent (y)
+func anisotropicGGX(roughness, anisotropic float64) microfacet {
+	aspect := math.Sqrt(1 - 0.9*anisotropic)
+	alpha := ggxAlpha(roughness)
+	return microfacet{alpha / aspect, alpha * aspect}
+}
+
+func (d microfacet) smooth() bool {
+	return math.Max(d.alphaX, d.alphaY) < smoothAlpha
+}
+
+// D returns the density of microfacets with normal h
+func (d microfacet) D(h Tuple) float64 {
+	if h.z <= 0 {
+		return 0
+	}
+	x, y := h.x/d.alphaX, h.y/d.alphaY
+	t := x*x + y*y + h.z*h.z
+	return 1 / (math.Pi * d.alphaX * d.alphaY * t * t)
+}
+
+// lambda is the auxiliary function of Smith masking
+func (d microfacet) lambda(w Tuple) float64 {
+	if w.z == 0 {
+		return math.Inf(1)
+	}
+	x, y := d.alphaX*w.x, d.alphaY*w.y
+	return (math.Sqrt(1+(x*x+y*y)/(w.z*w.z)) - 1) / 2
+}
+
+// G1 is the fraction of microfacets visible from w
+func (d microfacet) G1(w Tuple) float64 {
+	return 1 / (1 + d.lambda(w))
+}
+
+// G is the height-correlated fraction of microfacets visible from both wo and wi
+func (d microfacet) G(wo, wi Tuple) float64 {
+	return 1 / (1 + d.lambda(wo) + d.lambda(wi))
+}
+
+// pdf returns the density of sampling the microfacet normal h visible from wo with sample
+func (d microfacet) pdf(wo, h Tuple) float64 {
+	if wo.z == 0 {
+		return 0
+	}
+	return d.G1(wo) / math.Abs(wo.z) * d.D(h) * math.Abs(wo.Dot(h))
+}
+
+// sample returns a microfacet normal visible from wo (Heitz 2018), wo can be below the surface
+func (d microfacet) sample(wo Tuple, u, v float64) Tuple {
+	// stretch the view direction to the configuration of a hemisphere
+	vh := Tuple{d.alphaX * wo.x, d.alphaY * wo.y, wo.z, 0}.Normalize()
+	if vh.z < 0 {
+		vh = vh.Negate()
+	}
+	t1 := Tuple{1, 0, 0, 0}
+	if lensq := vh.x*vh.x + vh.y*vh.y; lensq > 0 {
+		t1 = Tuple{-vh.y, vh.x, 0, 0}.DivScalar(math.Sqrt(lensq))
+	}
+	t2 := vh.Cross(t1)
+
+	// sample the projected area of the visible hemisphere
+	r := math.Sqrt(u)
+	phi := 2 * math.Pi * v
+	p1, p2 := r*math.Cos(phi), r*math.Sin(phi)
+	s := (1 + vh.z) / 2
+	p2 = (1-s)*math.Sqrt(math.Max(0, 1-p1*p1)) + s*p2
+	nh := t1.MulScalar(p1).Add(t2.MulScalar(p2)).Add(vh.MulScalar(math.Sqrt(math.Max(0, 1-p1*p1-p2*p2))))
+
+	// and stretch the normal back
+	return Tuple{d.alphaX * nh.x, d.alphaY * nh.y, math.Max(1e-6, nh.z), 0}.Normalize()
+}
+
+// reflect returns w reflected on the plane with normal n, both point away from the surface
+func reflect(w, n Tuple) Tuple {
+	return n.MulScalar(2 * w.Dot(n)).Subtract(w)
+}
+
+// refract returns w refracted through the surface with normal n, eta is the ratio of indices of
+// refraction below and above the surface. It also returns the ratio of indices on the sides of the
+// refracted and incident direction, ok is false for total internal reflection
+func refract(w, n Tuple, eta float64) (wt Tuple, etap float64, ok bool) {
+	cosI := n.Dot(w)
+	if cosI < 0 {
+		eta = 1 / eta
+		cosI = -cosI
+		n = n.Negate()
+	}
+	sin2T := math.Max(0, 1-cosI*cosI) / (eta * eta)
+	if sin2T >= 1 {
+		return Tuple{}, 0, false
+	}
+	cosT := math.Sqrt(1 - sin2T)
+	return w.Negate().DivScalar(eta).Add(n.MulScalar(cosI/eta - cosT)), eta, true
+}
+
+// frDielectric returns Fresnel reflectance of unpolarized light on a dielectric, cosI is the
+// cosine of the incident direction with the normal, negative below the surface
+func frDielectric(cosI, eta float64) float64 {
+	cosI = math.Max(-1, math.Min(1, cosI))
+	if cosI < 0 {
+		eta = 1 / eta
+		cosI = -cosI
+	}
+	sin2T := (1 - cosI*cosI) / (eta * eta)
+	if sin2T >= 1 {
+		return 1
+	}
+	cosT := math.Sqrt(1 - sin2T)
+	parallel := (eta*cosI - cosT) / (eta*cosI + cosT)
+	perpendicular := (cosI - eta*cosT) / (cosI + eta*cosT)
+	return (parallel*parallel + perpendicular*perpendicular) / 2
+}
+
+// schlickColor is Schlick's approximation of Fresnel reflectance with reflectance f0 at normal incidence
+func schlickColor(f0 Color, cos float64) Color {
+	w := math.Pow(math.Max(0, 1-cos), 5)
+	return f0.MulScalar(1 - w).Add(Color{w, w, w})
+}
+
+// Lobes of BSDFs below work in the local shading frame and return the BSDF multiplied by the
+// cosine of wi. Perfectly smooth distributions aren't evaluated, they're sampled as specular lobes
+
+// microfacetReflection returns reflection without Fresnel, wo and wi have to be above the surface
+func microfacetReflection(d microfacet, wo, wi Tuple) float64 {
+	if wo.z <= 0 || wi.z <= 0 || d.smooth() {
+		return 0
+	}
+	h := wo.Add(wi).Normalize()
+	return d.D(h) * d.G(wo, wi) / (4 * wo.z)
+}
+
+// microfacetReflectionPdf returns the density of sampling wi by reflecting wo on a visible microfacet
+func microfacetReflectionPdf(d microfacet, wo, wi Tuple) float64 {
+	if wo.z <= 0 || wi.z <= 0 || d.smooth() {
+		return 0
+	}
+	h := wo.Add(wi).Normalize()
+	return d.pdf(wo, h) / (4 * wo.Dot(h))
+}
+
+// dielectricHalfVector returns the generalized half vector of wo and wi (Walter et al. 2007)
+// on a rough dielectric with relative index of refraction eta, ok is false for invalid configurations
+func dielectricHalfVector(wo, wi Tuple, eta float64) (h Tuple, etap float64, ok bool) {
+	reflection := wo.z*wi.z > 0
+	etap = 1
+	if !reflection {
+		etap = eta
+		if wo.z < 0 {
+			etap = 1 / eta
+		}
+	}
+	h = wi.MulScalar(etap).Add(wo)
+	if wo.z == 0 || wi.z == 0 || h.Dot(h) == 0 {
+		return Tuple{}, 0, false
+	}
+	h = h.Normalize()
+	if h.z < 0 {
+		h = h.Negate()
+	}
+	// microfacets facing away from either direction can't connect them
+	if h.Dot(wi)*wi.z < 0 || h.Dot(wo)*wo.z < 0 {
+		return Tuple{}, 0, false
+	}
+	return h, etap, true
+}
+
+// roughDielectric returns reflection and transmission of a rough dielectric, wo and wi can be on
+// both sides of the surface, the normal points to the side with index of refraction 1 and the film
+func roughDielectric(d microfacet, wo, wi Tuple, eta float64, film thinFilm) (reflection, transmission Color) {
+	if d.smooth() {
+		return Color{0, 0, 0}, Color{0, 0, 0}
+	}
+	h, etap, ok := dielectricHalfVector(wo, wi, eta)
+	if !ok {
+		return Color{0, 0, 0}, Color{0, 0, 0}
+	}
+	f := film.dielectric(wo.Dot(h), eta)
+	if wo.z*wi.z > 0 {
+		return f.MulScalar(d.D(h) * d.G(wo, wi) / (4 * math.Abs(wo.z))), Color{0, 0, 0}
+	}
+	denominator := wi.Dot(h) + wo.Dot(h)/etap
+	t := d.D(h) * d.G(wo, wi) * math.Abs(wi.Dot(h)*wo.Dot(h)/(wo.z*denominator*denominator))
+	return Color{0, 0, 0}, Color{1, 1, 1}.Subtract(f).MulScalar(t)
+}
+
+// roughDielectricPdf returns the density of sampling wi from wo on a rough dielectric, reflection
+// and refraction are chosen by Fresnel reflectance of the visible microfacet, the mean of the channels with a film
+func roughDielectricPdf(d microfacet, wo, wi Tuple, eta float64, film thinFilm) float64 {
+	if d.smooth() {
+		return 0
+	}
+	h, etap, ok := dielectricHalfVector(wo, wi, eta)
+	if !ok {
+		return 0
+	}
+	f := film.dielectric(wo.Dot(h), eta).mean()
+	if wo.z*wi.z > 0 {
+		return d.pdf(wo, h) / (4 * math.Abs(wo.Dot(h))) * f
+	}
+	denominator := wi.Dot(h) + wo.Dot(h)/etap
+	return d.pdf(wo, h) * math.Abs(wi.Dot(h)) / (denominator * denominator) * (1 - f)
+}
+
+// sampleDielectric samples a direction reflected or refracted by a rough or smooth dielectric,
+// reflected is set for reflected directions. Without a film the probability of reflection is its
+// Fresnel reflectance, otherwise it's the mean of the channels and weight is the reflectance or
+// transmittance of the channels divided by the probability, which is needed for smooth dielectrics
+func sampleDielectric(d microfacet, wo Tuple, eta float64, film thinFilm, u, v, choice float64) (wi Tuple, weight Color, reflected bool, ok bool) {
+	h := Tuple{0, 0, 1, 0}
+	if !d.smooth() {
+		h = d.sample(wo, u, v)
+	}
+	f := film.dielectric(wo.Dot(h), eta)
+	if p := f.mean(); choice < p {
+		wi = reflect(wo, h)
+		return wi, f.DivScalar(p), true, wi.z*wo.z > 0
+	}
+	wi, _, ok = refract(wo, h, eta)
+	return wi, Color{1, 1, 1}.Subtract(f).DivScalar(1 - f.mean()), false, ok && wi.z*wo.z < 0
+}
+
+// thinDielectric returns reflection and transmission of a rough thin dielectric sheet with index of
+// refraction eta. Light passes through both of its sides without changing direction, so transmission
+// is reflection mirrored to the other side (Burley 2015)
+func thinDielectric(d microfacet, wo, wi Tuple, eta float64) (reflection, transmission float64) {
+	if wo.z < 0 {
+		wo.z, wi.z = -wo.z, -wi.z
+	}
+	transmitted := wi.z < 0
+	wi.z = math.Abs(wi.z)
+	r := microfacetReflection(d, wo, wi)
+	if r == 0 {
+		return 0, 0
+	}
+	f := frDielectric(wo.Dot(wo.Add(wi).Normalize()), eta)
+	if transmitted {
+		return 0, (1 - f) * r
+	}
+	return f * r, 0
+}
+
+// thinDielectricPdf returns the density of sampling wi from wo on a rough thin dielectric sheet
+func thinDielectricPdf(d microfacet, wo, wi Tuple, eta float64) float64 {
+	if wo.z < 0 {
+		wo.z, wi.z = -wo.z, -wi.z
+	}
+	transmitted := wi.z < 0
+	wi.z = math.Abs(wi.z)
+	pdf := microfacetReflectionPdf(d, wo, wi)
+	if pdf == 0 {
+		return 0
+	}
+	f := frDielectric(wo.Dot(wo.Add(wi).Normalize()), eta)
+	if transmitted {
+		return (1 - f) * pdf
+	}
+	return f * pdf
+}
+
+// sampleThinDielectric samples a direction reflected or transmitted by a rough or smooth thin dielectric
+// sheet, reflected is set for reflected directions, whose probability is Fresnel reflectance
+func sampleThinDielectric(d microfacet, wo Tuple, eta float64, u, v, choice float64) (wi Tuple, reflected, ok bool) {
+	side := 1.0
+	if wo.z < 0 {
+		side = -1
+	}
+	wo.z *= side
+	h := Tuple{0, 0, 1, 0}
+	if !d.smooth() {
+		h = d.sample(wo, u, v)
+	}
+	wi = reflect(wo, h)
+	ok = wi.z > 0
+	reflected = choice < frDielectric(wo.Dot(h), eta)
+	if !reflected {
+		wi.z = -wi.z
+	}
+	wi.z *= side
+	return wi, reflected, ok
+}
+
+// orenNayar returns the Oren-Nayar diffuse BSDF divided by albedo and multiplied by the cosine of wi,
+// wo and wi are normalized and on the same side of the surface in the local frame. sigma is the
+// standard deviation of slopes of microfacets in degrees, at zero it's Lambertian reflection
+func orenNayar(sigma float64, wo, wi Tuple) float64 {
+	cosI, cosO := math.Abs(wi.z), math.Abs(wo.z)
+	if sigma <= 0 || cosI == 0 || cosO == 0 {
+		return cosI / math.Pi
+	}
+	s2 := sigma * math.Pi / 180
+	s2 *= s2
+	a := 1 - s2/(2*(s2+0.33))
+	b := 0.45 * s2 / (s2 + 0.09)
+
+	// the retro-reflective term grows when wi and wo are on the same side of the normal
+	sinI, sinO := math.Sqrt(math.Max(0, 1-cosI*cosI)), math.Sqrt(math.Max(0, 1-cosO*cosO))
+	maxCos := 0.0
+	if sinI > 1e-4 && sinO > 1e-4 {
+		maxCos = math.Max(0, (wi.x*wo.x+wi.y*wo.y)/(sinI*sinO))
+	}
+	var sinAlpha, tanBeta float64
+	if cosI > cosO {
+		sinAlpha, tanBeta = sinO, sinI/cosI
+	} else {
+		sinAlpha, tanBeta = sinI, sinO/cosO
+	}
+	return (a + b*maxCos*sinAlpha*tanBeta) * cosI / math.Pi
+}
@@ -0,0 +1,170 @@
+package pt
+
+import (
+	"fmt"
+	"math"
+)
+
+// mixture chooses one of two materials at every hit
+type mixture struct {
+	a, b Material
+	// weight is the probability of b, the red channel of the texture, unless fresnel is set,
+	// then it's Fresnel reflectance of a dielectric with that index of refraction
+	weight  Texture
+	fresnel float64
+}
+
+// NewMix returns a material which is a where weight is 0 and b where it's 1, weight is the red
+// channel of the texture, like a mask of rust over paint. One of the materials is chosen randomly
+// at every hit, so both can be of any type
+func NewMix(a, b Material, weight Texture) Material {
+	return Material{material: Mix, albedo: a.albedo, mix: &mixture{a, b, weight, 0}}
+}
+
+// NewFresnelMix returns a material which is b in proportion to Fresnel reflectance of a dielectric
+// with index of refraction ior and a otherwise, so b shows at grazing angles
+func NewFresnelMix(a, b Material, ior float64) Material {
+	return Material{material: Mix, albedo: a.albedo, mix: &mixture{a, b, Texture{}, ior}}
+}
+
+func (x *mixture) String() string {
+	if x.fresnel > 0 {
+		return fmt.Sprintf("(%v) and (%v) by fresnel with ior %g", x.a, x.b, x.fresnel)
+	}
+	return fmt.Sprintf("(%v) and (%v) by weight %v", x.a, x.b, x.weight)
+}
+
+// probability returns the probability of choosing b for light leaving the hit towards wo
+func (x *mixture) probability(rec HitRecord, wo Tuple) float64 {
+	if x.fresnel > 0 {
+		return frDielectric(math.Abs(wo.Normalize().Dot(rec.normal)), x.fresnel)
+	}
+	return math.Max(0, math.Min(1, x.weight.value(rec)))
+}
+
+// pick returns the material a Mix is at the hit for light leaving towards wo, other materials are
+// returned as they are. The choice is random with the probability of the weight, but it depends only
+// on the hit point, wo and the key of the hit, so Eval, Pdf and Scatter see the same material and
+// samples of a pixel see different ones. colorize records the choice in the hit
+func (m Material) pick(rec HitRecord, wo Tuple) Material {
+	if m.material != Mix {
+		return m
+	}
+	u := hashRay(Ray{rec.p, wo}, 0, rec.key)
+	for m.material == Mix {
+		// u is reused by nested mixes after stretching the part below or above the weight
+		w := m.mix.probability(rec, wo)
+		if u < w {
+			m, u = m.mix.b, u/w
+		} else {
+			m, u = m.mix.a, (u-w)/(1-w)
+		}
+	}
+	return m
+}
+
+// coating is a dielectric coat over a base material, light passing through it to the base
+// and back is absorbed along the way
+type coating struct {
+	base                      Material
+	roughness, ior, thickness float64
+	absorption                Color
+}
+
+// NewLayered returns the base material under a dielectric coat with roughness and index of refraction
+// ior, the base can be of any type which scatters light at its surface, even another layered material.
+// Light reaching the base is absorbed inside the coat of the given thickness, absorption is the
+// coefficient per unit of distance for every channel. It's the general form of clearcoat of the
+// universal material, which is a clear coat with index of refraction 1.5
+func NewLayered(base Material, roughness, ior, thickness float64, absorption Color) Material {
+	return Material{material: Layered, albedo: base.albedo, roughness: roughness, ior: ior,
+		layer: &coating{base, roughness, ior, thickness, absorption}}
+}
+
+func (c *coating) String() string {
+	return fmt.Sprintf("coat roughness %g, ior %g, thickness %g, absorption %v over (%v)",
+		c.roughness, c.ior, c.thickness, c.absorption, c.base)
+}
+
+// transmittance returns the fraction of light passing through the coat to the base from the direction
+// at cosine cosO with the normal and back towards cosI, refracted twice and absorbed along both paths
+func (c *coating) transmittance(cosO, cosI float64) Color {
+	t := (1 - frDielectric(math.Abs(cosO), c.ior)) * (1 - frDielectric(math.Abs(cosI), c.ior))
+	if c.thickness <= 0 || c.absorption == (Color{0, 0, 0}) {
+		return Color{t, t, t}
+	}
+	// paths are longer for directions refracted further from the normal
+	refracted := func(cos float64) float64 {
+		return math.Sqrt(math.Max(1e-6, 1-(1-cos*cos)/(c.ior*c.ior)))
+	}
+	distance := c.thickness * (1/refracted(cosO) + 1/refracted(cosI))
+	return medium{absorption: c.absorption}.transmittance(distance).MulScalar(t)
+}
+
+// eval evaluates reflection by the coat and light scattered by the base through it, wo and wi
+// are in world space like in Material.Eval. The coat reflects light on both sides
+func (c *coating) eval(rec HitRecord, wo, wi Tuple) Color {
+	frame := rec.frame()
+	lo, li := frame.coordinates(wo), frame.coordinates(wi)
+	f := c.base.Eval(rec, wo, wi).Mul(c.transmittance(lo.z, li.z))
+	if lo.z < 0 {
+		lo.z, li.z = -lo.z, -li.z
+	}
+	if li.z > 0 {
+		h := lo.Add(li).Normalize()
+		r := frDielectric(lo.Dot(h), c.ior) * microfacetReflection(isotropic(ggxAlpha(c.roughness)), lo, li)
+		f = f.Add(Color{r, r, r})
+	}
+	return f
+}
+
+// pdf returns the density of scatterLayered sampling wi, the coat is sampled with the probability of its reflectance
+func (c *coating) pdf(rec HitRecord, wo, wi Tuple) float64 {
+	frame := rec.frame()
+	lo, li := frame.coordinates(wo), frame.coordinates(wi)
+	coat := frDielectric(math.Abs(lo.z), c.ior)
+	pdf := (1 - coat) * c.base.Pdf(rec, wo, wi)
+	if lo.z < 0 {
+		lo.z, li.z = -lo.z, -li.z
+	}
+	if li.z > 0 {
+		pdf += coat * microfacetReflectionPdf(isotropic(ggxAlpha(c.roughness)), lo, li)
+	}
+	return pdf
+}
+
+// scatterLayered samples either the coat or the base of a Layered material
+func (m Material) scatterLayered(r Ray, rec HitRecord, sampler Sampler, srec *ScatterRecord) bool {
+	c := m.layer
+	frame := rec.frame()
+	wo := frame.coordinates(r.direction.Normalize().Negate())
+	side := 1.0
+	if wo.z < 0 {
+		side = -1
+	}
+	above := Tuple{wo.x, wo.y, wo.z * side, 0}
+	coat := frDielectric(above.z, c.ior)
+	if sampler.Get1D() < coat {
+		d := isotropic(ggxAlpha(c.roughness))
+		if d.smooth() {
+			// the probability of the coat is its reflectance
+			return m.scatterSpecular(rec, frame, Tuple{-wo.x, -wo.y, wo.z, 0}, Color{1, 1, 1}, srec)
+		}
+		u, v := sampler.Get2D()
+		wi := reflect(above, d.sample(above, u, v))
+		wi.z *= side
+		return m.scatterSampled(rec, frame, wo, wi, srec)
+	}
+	if !c.base.Scatter(r, rec, sampler, srec) {
+		return false
+	}
+	// light passing the coat isn't followed inside subsurface bases
+	srec.subsurface = false
+	wi := frame.coordinates(srec.ray.direction.Normalize())
+	if srec.specular {
+		// the base was chosen with the probability of light entering the coat
+		srec.attenuation = srec.attenuation.Mul(c.transmittance(wo.z, wi.z)).DivScalar(1 - coat)
+		return true
+	}
+	return m.scatterSampled(rec, frame, wo, wi, srec)
+}
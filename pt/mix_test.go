@@ -0,0 +1,85 @@
+package pt
+
+import (
+	"math"
+	"testing"
+)
+
+// TestMixPick checks that materials of mixes are chosen in proportion to their weights,
+// also when mixes are nested and for different samples of the same hit
+func TestMixPick(t *testing.T) {
+	a, b, c := NewLambertian(NewConstant(Color{1, 0, 0})), NewLambertian(NewConstant(Color{0, 1, 0})), NewLambertian(NewConstant(Color{0, 0, 1}))
+	mix := NewMix(a, NewMix(b, c, gray(0.5)), gray(0.4))
+	normal := Tuple{0, 0, 1, 0}
+	rng := NewRNG(1, 0)
+	counts := Color{0, 0, 0}
+	n := 20000
+	for i := 0; i < n; i++ {
+		rec := HitRecord{p: Tuple{RandFloat(rng), RandFloat(rng), 0, 1}, normal: normal}
+		picked := mix.pick(rec, normal).albedo.color(rec)
+		if again := mix.pick(rec, normal).albedo.color(rec); again != picked {
+			t.Fatalf("different materials picked at %v", rec.p)
+		}
+		counts = counts.Add(picked)
+	}
+	counts = counts.DivScalar(float64(n))
+	if math.Abs(counts.r-0.6) > 0.02 || math.Abs(counts.g-0.2) > 0.02 || math.Abs(counts.b-0.2) > 0.02 {
+		t.Errorf("materials picked with probabilities %v, expected (0.6, 0.2, 0.2)", counts)
+	}
+
+	// samples of the same point and direction pick materials by their keys
+	counts = Color{0, 0, 0}
+	for i := 0; i < n; i++ {
+		rec := HitRecord{p: Tuple{0.5, 0.5, 0, 1}, normal: normal, key: mix64(uint64(i))}
+		counts = counts.Add(mix.pick(rec, normal).albedo.color(rec))
+	}
+	counts = counts.DivScalar(float64(n))
+	if math.Abs(counts.r-0.6) > 0.02 || math.Abs(counts.g-0.2) > 0.02 || math.Abs(counts.b-0.2) > 0.02 {
+		t.Errorf("materials picked with probabilities %v at the same point, expected (0.6, 0.2, 0.2)", counts)
+	}
+
+	// the second material of a Fresnel mix shows at grazing angles
+	fresnel := NewFresnelMix(a, b, 1.5)
+	picked := func(cos float64) float64 {
+		wo := Tuple{math.Sqrt(1 - cos*cos), 0, cos, 0}
+		sum := 0.0
+		for i := 0; i < n; i++ {
+			rec := HitRecord{p: Tuple{RandFloat(rng), RandFloat(rng), 0, 1}, normal: normal}
+			sum += fresnel.pick(rec, wo).albedo.color(rec).g
+		}
+		return sum / float64(n)
+	}
+	if normal, grazing := picked(1), picked(0.05); math.Abs(normal-0.04) > 0.01 || grazing < 0.5 {
+		t.Errorf("fresnel mix picks the second material with probability %g at normal incidence and %g at grazing angles", normal, grazing)
+	}
+}
+
+// TestLayered checks that a coat matching the outside doesn't change the base, and that
+// absorption inside a coat tints light reaching the base
+func TestLayered(t *testing.T) {
+	base := NewGlossy(NewConstant(Color{0.8, 0.8, 0.8}), 0.3, 0)
+	rec := HitRecord{p: Tuple{0, 0, 0, 1}, normal: Tuple{0, 0, 1, 0}, tangent: Tuple{1, 0, 0, 0}}
+	wo, wi := Tuple{0.6, 0, 0.8, 0}, Tuple{-0.3, 0.2, 0.9, 0}.Normalize()
+	clear := NewLayered(base, 0.2, 1, 1, Color{0, 0, 0})
+	if f, g := clear.Eval(rec, wo, wi), base.Eval(rec, wo, wi); !Equal(f.r, g.r) || !Equal(f.g, g.g) || !Equal(f.b, g.b) {
+		t.Errorf("BSDF %v under a coat with ior 1, %v without it", f, g)
+	}
+	if p, q := clear.Pdf(rec, wo, wi), base.Pdf(rec, wo, wi); !Equal(p, q) {
+		t.Errorf("pdf %g under a coat with ior 1, %g without it", p, q)
+	}
+	tinted := NewLayered(base, 0.2, 1, 0.5, Color{0, 1, 2}).Eval(rec, wo, wi)
+	if tinted.r <= tinted.g || tinted.g <= tinted.b {
+		t.Errorf("light absorbed mostly in blue and green is tinted %v", tinted)
+	}
+
+	// maps of roughness and ior change the coat
+	roughness, ior := gray(0.4), gray(1.6)
+	mapped := NewLayered(base, 0.2, 1, 0, Color{0, 0, 0}).WithMaps(MaterialMaps{Roughness: &roughness, IOR: &ior})
+	coated := NewLayered(base, 0.4, 1.6, 0, Color{0, 0, 0})
+	if f, g := mapped.Eval(rec, wo, wi), coated.Eval(rec, wo, wi); !Equal(f.r, g.r) || !Equal(f.g, g.g) || !Equal(f.b, g.b) {
+		t.Errorf("BSDF %v with maps of the coat, %v with the same constants", f, g)
+	}
+	if p, q := mapped.Pdf(rec, wo, wi), coated.Pdf(rec, wo, wi); !Equal(p, q) {
+		t.Errorf("pdf %g with maps of the coat, %g with the same constants", p, q)
+	}
+}
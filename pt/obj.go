@@ -0,0 +1,466 @@
+package pt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func loadMaterial(file *os.File, name, dir string, imageArray *[]ImageHash) (Material, error) {
+	material := Material{material: Lambertian}
+	// parameters of the PBR extension of MTL make it a principled material
+	principled := DefaultPrincipled()
+	pbr, hasRoughness := false, false
+	// maps of scalar parameters and emission are given by images separately from Kd
+	var maps MaterialMaps
+	var emission *Texture
+	// dissolve (d) is the transparency of glass for illumination models with refraction,
+	// otherwise it's opacity, which cuts out leaves and fences together with alpha of map_Kd
+	dissolve, refractive := 1.0, false
+	var dissolveMap, albedoAlpha [][]Color
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		text := strings.Fields(scanner.Text())
+		if len(text) > 0 {
+			if text[0] == "newmtl" && text[1] == name {
+				for scanner.Scan() {
+					text = strings.Fields(scanner.Text())
+					if len(text) > 0 {
+						if text[0] == "newmtl" {
+							break
+						}
+						if text[0] == "Ke" {
+							r, _ := strconv.ParseFloat(text[1], 64)
+							g, _ := strconv.ParseFloat(text[2], 64)
+							b, _ := strconv.ParseFloat(text[3], 64)
+							if r > 0.0 || g > 0.0 || b > 0.0 {
+								material.albedo = NewConstant(Color{r, g, b})
+								material.material = Emission
+							}
+						}
+						if text[0] == "Kd" {
+							r, _ := strconv.ParseFloat(text[1], 64)
+							g, _ := strconv.ParseFloat(text[2], 64)
+							b, _ := strconv.ParseFloat(text[3], 64)
+							material.albedo = NewConstant(Color{r, g, b})
+						}
+						if text[0] == "Ks" {
+							r, _ := strconv.ParseFloat(text[1], 64)
+							g, _ := strconv.ParseFloat(text[2], 64)
+							b, _ := strconv.ParseFloat(text[3], 64)
+							material.clearcoat = (r + g + b) / 3
+						}
+						if text[0] == "Ns" {
+							roughness, _ := strconv.ParseFloat(text[1], 64)
+							x1, _ := solveQuadratic(900, -1800, 900-roughness)
+							if x1 > 1.0 {
+								x1 = 1.0
+							} else if x1 < 0.0 {
+								x1 = 0.0
+							}
+							material.roughness = x1
+							material.clearcoatRoughness = x1
+						}
+						if text[0] == "sigma" {
+							// rough diffuse reflection (Oren-Nayar) isn't a part of MTL, sigma is in degrees
+							sigma, _ := strconv.ParseFloat(text[1], 64)
+							material.sigma = sigma
+						}
+						if text[0] == "Ni" {
+							ior, _ := strconv.ParseFloat(text[1], 64)
+							material.ior = ior
+						}
+						if text[0] == "d" {
+							dissolve, _ = strconv.ParseFloat(text[1], 64)
+						}
+						if text[0] == "Tr" {
+							transparency, _ := strconv.ParseFloat(text[1], 64)
+							dissolve = 1 - transparency
+						}
+						if text[0] == "Tf" && len(text) >= 4 {
+							// transmission filter is the color left after a unit of distance in the medium
+							r, _ := strconv.ParseFloat(text[1], 64)
+							g, _ := strconv.ParseFloat(text[2], 64)
+							b, _ := strconv.ParseFloat(text[3], 64)
+							material = material.WithTransmittance(Color{r, g, b}, 1)
+						}
+						if value, ok := pbrParameters(&principled)[text[0]]; ok {
+							v, _ := strconv.ParseFloat(text[1], 64)
+							*value = gray(v)
+							if len(text) >= 4 {
+								g, _ := strconv.ParseFloat(text[2], 64)
+								b, _ := strconv.ParseFloat(text[3], 64)
+								*value = NewConstant(Color{v, g, b})
+							}
+							pbr = true
+							hasRoughness = hasRoughness || text[0] == "Pr"
+						}
+						if text[0] == "Pcr" {
+							roughness, _ := strconv.ParseFloat(text[1], 64)
+							principled.ClearcoatGloss = gray(1 - roughness)
+							pbr = true
+						}
+						if value, ok := pbrParameters(&principled)[strings.TrimPrefix(text[0], "map_")]; ok && strings.HasPrefix(text[0], "map_") {
+							path := filepath.Join(dir, text[len(text)-1])
+							if fileExists(path) {
+								texture, err := getTexture(path, imageArray)
+								if err != nil {
+									return material, err
+								}
+								*value = Texture{mode: TriangleImageUV, diffuseTexture: texture}
+								pbr = true
+								hasRoughness = hasRoughness || text[0] == "map_Pr"
+							}
+						}
+						if text[0] == "illum" {
+							if material.material != Emission {
+								mode, _ := strconv.ParseInt(text[1], 0, 0)
+								switch mode {
+								case 1:
+									material.material = Lambertian
+								case 2, 9:
+									material.material = BSDF
+								case 4, 6, 7:
+									material.material = BSDF
+									refractive = true
+								case 3:
+									material.material = BSDF
+									material.metalicity = 1.0
+								}
+							}
+						}
+						if text[0] == "map_Ke" || text[0] == "map_Ns" || text[0] == "map_d" {
+							path := filepath.Join(dir, text[len(text)-1])
+							if fileExists(path) {
+								texture, err := getTexture(path, imageArray)
+								if err != nil {
+									return material, err
+								}
+								switch text[0] {
+								case "map_Ke":
+									emission = &Texture{mode: TriangleImageUV, diffuseTexture: texture}
+								case "map_Ns":
+									// exporters write roughness maps here rather than specular exponents
+									maps.Roughness = &Texture{mode: TriangleImageUV, diffuseTexture: texture}
+									maps.ClearcoatRoughness = maps.Roughness
+								case "map_d":
+									// the mask is either the alpha channel or a gray image
+									dissolveMap = texture
+									if alpha, _ := getAlpha(path, imageArray); alpha != nil {
+										dissolveMap = alpha
+									}
+								}
+							}
+						}
+						if text[0] == "map_Kd" {
+							path := filepath.Join(dir, text[1])
+							if fileExists(path) {
+								texture, err := getTexture(path, imageArray)
+								if err != nil {
+									return material, err
+								}
+								material.albedo.diffuseTexture = texture
+								material.albedo.mode = TriangleImageUV
+								albedoAlpha, _ = getAlpha(path, imageArray)
+							}
+						}
+						if text[0] == "map_Bump" || text[0] == "map_bump" || text[0] == "bump" {
+							path := filepath.Join(dir, text[1])
+							if fileExists(path) {
+								texture, err := getTexture(path, imageArray)
+								if err != nil {
+									return material, err
+								}
+								material.albedo.normalTexture = texture
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	var opacity *Texture
+	if refractive {
+		material.transmission = 1 - dissolve
+		if dissolveMap != nil {
+			maps.Transmission = &Texture{mode: TriangleImageUV, diffuseTexture: invert(dissolveMap)}
+		}
+	} else if dissolveMap != nil {
+		opacity = &Texture{mode: TriangleImageUV, diffuseTexture: dissolveMap}
+	} else if dissolve < 1 {
+		constant := gray(dissolve)
+		opacity = &constant
+	} else if albedoAlpha != nil {
+		opacity = &Texture{mode: TriangleImageUV, diffuseTexture: albedoAlpha}
+	}
+	if pbr && material.material != Emission {
+		principled.BaseColor = material.albedo
+		if material.ior > 0 {
+			principled.IOR = gray(material.ior)
+		}
+		principled.SpecTrans = gray(material.transmission)
+		if maps.Transmission != nil {
+			principled.SpecTrans = *maps.Transmission
+		}
+		if !hasRoughness {
+			principled.Roughness = gray(material.roughness)
+			if maps.Roughness != nil {
+				principled.Roughness = *maps.Roughness
+			}
+		}
+		material = NewPrincipled(principled)
+	} else if maps != (MaterialMaps{}) {
+		material = material.WithMaps(maps)
+	}
+	if emission != nil {
+		material = NewEmission(*emission)
+	}
+	if opacity != nil {
+		material = material.WithOpacity(*opacity, 0)
+	}
+	return material, scanner.Err()
+}
+
+// pbrParameters maps parameters of the PBR extension of MTL to parameters of the principled material,
+// clearcoat roughness (Pcr) is converted to gloss. The extension has no parameters of thin surfaces and
+// scattering, they're named like the others: thin (Pth), diffuse transmission (Pdt), flatness (Pfl)
+// and scatter distance (Psd), which is a color
+func pbrParameters(p *PrincipledParams) map[string]*Texture {
+	return map[string]*Texture{
+		"Pr":     &p.Roughness,
+		"Pm":     &p.Metallic,
+		"Ps":     &p.Sheen,
+		"Pc":     &p.Clearcoat,
+		"aniso":  &p.Anisotropic,
+		"anisor": &p.AnisotropicRotation,
+		"Pth":    &p.Thin,
+		"Pdt":    &p.DiffTrans,
+		"Pfl":    &p.Flatness,
+		"Psd":    &p.ScatterDistance,
+	}
+}
+
+func fileExists(path string) bool {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false
+	}
+	return true
+}
+
+func loadOBJ(path string, list *[][]Triangle, transform Transform, imageArray *[]ImageHash, materialArray *[]MaterialHash, material Material, smooth, overrideMaterial bool) error {
+	log.Printf("Loading 3D scene from %v file\n", path)
+	vertices := []Tuple{}
+	vertNormals := []Tuple{}
+	vertTexture := []Tuple{}
+	faceVerts := []TrianglePosition{}
+	faceNormals := []TrianglePosition{}
+	faceTexture := []TrianglePosition{}
+	var materialFile *os.File
+	dir := filepath.Dir(path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	f := 0
+
+	exists := false
+
+	object := []Triangle{}
+	start := len(*list)
+
+	defer file.Close()
+	defer materialFile.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		materialFile.Seek(0, io.SeekStart)
+		text := strings.Fields(scanner.Text())
+		if len(text) > 0 {
+			if text[0] == "o" {
+				if len(object) > 0 {
+					*list = append(*list, object)
+					object = nil
+				}
+			}
+			if !overrideMaterial {
+				if text[0] == "mtllib" {
+					mtlPath := filepath.Join(dir, text[1])
+					if fileExists(mtlPath) {
+						log.Printf("Opening material library file %s", mtlPath)
+						materialFile, _ = os.Open(mtlPath)
+						exists = true
+					} else {
+						exists = false
+					}
+				}
+				if text[0] == "usemtl" {
+					if exists {
+						strHash := hash(text[1])
+						result := wasMaterialLoaded(strHash, *materialArray)
+						if result == -1 {
+							log.Printf("Loading new material: %s", text[1])
+							material, err = loadMaterial(materialFile, text[1], dir, imageArray)
+							if err != nil {
+								return fmt.Errorf("%s: material %s: %w", path, text[1], err)
+							}
+
+							*materialArray = append(*materialArray, MaterialHash{
+								material, strHash, text[1],
+							})
+						} else {
+							material = (*materialArray)[result].material
+						}
+					}
+				}
+			}
+			if text[0] == "v" {
+				x, _ := strconv.ParseFloat(text[1], 64)
+				y, _ := strconv.ParseFloat(text[2], 64)
+				z, _ := strconv.ParseFloat(text[3], 64)
+				vertices = append(vertices, Tuple{
+					x, y, z, 0,
+				})
+			} else if text[0] == "vn" {
+				x, _ := strconv.ParseFloat(text[1], 64)
+				y, _ := strconv.ParseFloat(text[2], 64)
+				z, _ := strconv.ParseFloat(text[3], 64)
+				vertNormals = append(vertNormals, Tuple{
+					x, y, z, 0,
+				})
+			} else if text[0] == "vt" {
+				u, _ := strconv.ParseFloat(text[1], 64)
+				v, _ := strconv.ParseFloat(text[2], 64)
+				vertTexture = append(vertTexture, Tuple{
+					u, v, 1, 0,
+				})
+			} else if text[0] == "f" {
+				vertCount := len(text) - 1
+				for i := 0; i < vertCount-2; i++ {
+					values1 := strings.Split(text[1], "/")
+					values2 := strings.Split(text[i+2], "/")
+					values3 := strings.Split(text[i+3], "/")
+
+					var v1, v2, v3, vt1, vt2, vt3, vn1, vn2, vn3 int
+
+					v1, _ = strconv.Atoi(values1[0])
+					v2, _ = strconv.Atoi(values2[0])
+					v3, _ = strconv.Atoi(values3[0])
+
+					if values1[1] != "" && values2[1] != "" && values3[1] != "" {
+						vt1, _ = strconv.Atoi(values1[1])
+						vt2, _ = strconv.Atoi(values2[1])
+						vt3, _ = strconv.Atoi(values3[1])
+
+						if vt1 < 0 {
+							vt1 = len(vertNormals) + vt1 + 1
+						}
+						if vt2 < 0 {
+							vt2 = len(vertNormals) + vt2 + 1
+						}
+						if vt3 < 0 {
+							vt3 = len(vertNormals) + vt3 + 1
+						}
+
+						faceTexture = append(faceTexture, TrianglePosition{
+							vertTexture[vt1-1], vertTexture[vt2-1], vertTexture[vt3-1],
+						})
+					} else {
+						faceTexture = append(faceTexture, TrianglePosition{})
+					}
+
+					if values1[2] != "" && values2[2] != "" && values3[2] != "" {
+						vn1, _ = strconv.Atoi(values1[2])
+						vn2, _ = strconv.Atoi(values2[2])
+						vn3, _ = strconv.Atoi(values3[2])
+
+						if vn1 < 0 {
+							vn1 = len(vertNormals) + vn1 + 1
+						}
+						if vn2 < 0 {
+							vn2 = len(vertNormals) + vn2 + 1
+						}
+						if vn3 < 0 {
+							vn3 = len(vertNormals) + vn3 + 1
+						}
+
+						faceNormals = append(faceNormals, TrianglePosition{
+							vertNormals[vn1-1], vertNormals[vn2-1], vertNormals[vn3-1],
+						})
+					} else {
+						faceNormals = append(faceNormals, TrianglePosition{})
+					}
+
+					if v1 < 0 {
+						v1 = len(vertices) + v1 + 1
+					}
+					if v2 < 0 {
+						v2 = len(vertices) + v2 + 1
+					}
+					if v3 < 0 {
+						v3 = len(vertices) + v3 + 1
+					}
+
+					faceVerts = append(faceVerts, TrianglePosition{
+						vertices[v1-1], vertices[v2-1], vertices[v3-1],
+					})
+
+					triangle := Triangle{
+						faceVerts[f],
+						faceTexture[f],
+						faceNormals[f],
+						TrianglePosition{},
+						material,
+						Tuple{0, 0, 0, 0},
+						Tuple{0, 0, 0, 0},
+						smooth,
+					}
+					edge1 := triangle.position.vertex1.Subtract(triangle.position.vertex0)
+					edge2 := triangle.position.vertex2.Subtract(triangle.position.vertex0)
+					triangle.geometric = edge1.Cross(edge2).Normalize()
+					if faceNormals[f] == (TrianglePosition{}) {
+						// without vertex normals the normal of the face is used
+						triangle.normal = triangle.geometric
+						triangle.vnormals = TrianglePosition{triangle.normal, triangle.normal, triangle.normal}
+					} else {
+						vertex0 := faceNormals[f].vertex0
+						vertex1 := faceNormals[f].vertex1
+						vertex2 := faceNormals[f].vertex2
+						triangle.normal = (vertex0.Add(vertex1).Add(vertex2)).Normalize()
+					}
+
+					// texture coordinates aren't transformed, normals use the inverse transpose
+					triangle.position.vertex0 = transform.Point(triangle.position.vertex0)
+					triangle.position.vertex1 = transform.Point(triangle.position.vertex1)
+					triangle.position.vertex2 = transform.Point(triangle.position.vertex2)
+					triangle.normal = transform.Normal(triangle.normal).Normalize()
+					triangle.geometric = transform.Normal(triangle.geometric).Normalize()
+					triangle.vnormals.vertex0 = transform.Normal(triangle.vnormals.vertex0).Normalize()
+					triangle.vnormals.vertex1 = transform.Normal(triangle.vnormals.vertex1).Normalize()
+					triangle.vnormals.vertex2 = transform.Normal(triangle.vnormals.vertex2).Normalize()
+
+					object = append(object, triangle)
+					f++
+				}
+			}
+		}
+	}
+
+	if len(object) > 0 {
+		*list = append(*list, object)
+		object = nil
+	}
+	for _, object := range (*list)[start:] {
+		smoothTangents(object)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
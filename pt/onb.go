@@ -0,0 +1,45 @@
+package pt
+
+type ONB struct {
+	u, v, w Tuple
+}
+
+func (o ONB) local(a Tuple) Tuple {
+	return o.u.MulScalar(a.x).Add(o.v.MulScalar(a.y)).Add(o.w.MulScalar(a.z))
+}
+
+func buildFromW(n Tuple) ONB {
+	var o ONB
+	var a Tuple
+
+	o.w = n.Normalize()
+	if o.w.x > 0.9 {
+		a = Tuple{0, 1, 0, 0}
+	} else {
+		a = Tuple{1, 0, 0, 0}
+	}
+
+	o.v = o.w.Cross(a).Normalize()
+	o.u = o.w.Cross(o.v)
+
+	return o
+}
+
+// buildFromWU returns a basis with w along n and u along the part of tangent perpendicular to n,
+// any basis around n is returned if tangent is parallel to it
+func buildFromWU(n, tangent Tuple) ONB {
+	var o ONB
+	o.w = n.Normalize()
+	u := tangent.Subtract(o.w.MulScalar(tangent.Dot(o.w)))
+	if u.Dot(u) < 1e-12 {
+		return buildFromW(n)
+	}
+	o.u = u.Normalize()
+	o.v = o.w.Cross(o.u)
+	return o
+}
+
+// coordinates returns a in the basis, the inverse of local
+func (o ONB) coordinates(a Tuple) Tuple {
+	return Tuple{a.Dot(o.u), a.Dot(o.v), a.Dot(o.w), 0}
+}
@@ -0,0 +1,52 @@
+package pt
+
+import (
+	"fmt"
+	"math"
+)
+
+// opacityMask cuts out parts of surfaces, for leaves, fences and decals. Rays pass through points
+// with opacity below the threshold, or with probability 1 - opacity if the threshold is zero, which
+// averages to partly transparent surfaces
+type opacityMask struct {
+	opacity   Texture
+	threshold float64
+}
+
+func (o *opacityMask) String() string {
+	if o.threshold > 0 {
+		return fmt.Sprintf("%v (threshold %g)", o.opacity, o.threshold)
+	}
+	return o.opacity.String()
+}
+
+// WithOpacity returns the material with surfaces cut out where opacity, the red channel of the
+// texture, is below threshold. If threshold is zero, rays pass through with probability 1 - opacity
+func (m Material) WithOpacity(opacity Texture, threshold float64) Material {
+	m.opacity = &opacityMask{opacity, threshold}
+	return m
+}
+
+// cutout checks if the ray passes through the surface at the hit, which needs only
+// the point, the distance, texture coordinates and the key
+func (m Material) cutout(r Ray, rec HitRecord) bool {
+	if m.opacity == nil {
+		return false
+	}
+	opacity := m.opacity.opacity.value(rec)
+	if m.opacity.threshold > 0 {
+		return opacity < m.opacity.threshold
+	}
+	return opacity < 1 && hashRay(r, rec.t, rec.key) >= opacity
+}
+
+// hashRay returns a number in [0, 1) which depends only on the ray, the distance along it and key,
+// so stochastic cutouts don't need a sampler during the search for hits, a ray sees the same surfaces
+// every time and renders can be repeated exactly. Keys of different samples give different numbers
+func hashRay(r Ray, t float64, key uint64) float64 {
+	h := mix64(key ^ mix64(math.Float64bits(t)))
+	for _, v := range [6]float64{r.origin.x, r.origin.y, r.origin.z, r.direction.x, r.direction.y, r.direction.z} {
+		h = mix64(h ^ math.Float64bits(v))
+	}
+	return float64(h>>11) / (1 << 53)
+}
@@ -0,0 +1,111 @@
+package pt
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// TestCutout checks that rays pass through cut out parts of triangles without changing
+// the closest hit found before
+func TestCutout(t *testing.T) {
+	// the left half of the triangle is cut out
+	opacity := NewCheckerboardUV(Color{0, 0, 0}, Color{1, 1, 1}, 0.5, 2)
+	tri := Triangle{
+		position:  TrianglePosition{Tuple{0, 0, 0, 0}, Tuple{1, 0, 0, 0}, Tuple{0, 1, 0, 0}},
+		vtexture:  TrianglePosition{Tuple{0, 0, 0, 0}, Tuple{1, 0, 0, 0}, Tuple{0, 1, 0, 0}},
+		normal:    Tuple{0, 0, 1, 0},
+		geometric: Tuple{0, 0, 1, 0},
+		material:  NewLambertian(NewConstant(Color{1, 1, 1})).WithOpacity(opacity, 0.5),
+	}
+	for _, test := range []struct {
+		x   float64
+		hit bool
+	}{{0.25, false}, {0.75, true}} {
+		rec := HitRecord{t: 5, uT: -1}
+		hit := tri.hit(Ray{Tuple{test.x, 0.1, 2, 0}, Tuple{0, 0, -1, 0}}, Epsilon, math.MaxFloat64, &rec)
+		if hit != test.hit {
+			t.Errorf("hit %v at x = %g, expected %v", hit, test.x, test.hit)
+		}
+		if !hit && (rec.t != 5 || rec.uT != -1) {
+			t.Errorf("cut out hit at x = %g changed the record to t %g, u %g", test.x, rec.t, rec.uT)
+		}
+	}
+
+	// the far side of a sphere is seen where the near one is cut out
+	s := Sphere{origin: Tuple{0, 0, 0, 0}, radius: 1,
+		material: NewLambertian(NewConstant(Color{1, 1, 1})).WithOpacity(NewCheckerboard(Color{0, 0, 0}, Color{1, 1, 1}, 1, 1, 1), 0.5)}
+	r := Ray{Tuple{0.5, 0.5, 5, 0}, Tuple{0, 0, -1, 0}}
+	rec := HitRecord{}
+	if !s.hit(r, Epsilon, math.MaxFloat64, &rec) || rec.p.z > 0 {
+		t.Errorf("expected a hit on the far side of the sphere, got %v", rec.p)
+	}
+
+	// materials of instances cut out triangles of their mesh
+	mesh := &Mesh{triangles: [][]Triangle{meshSphere(Tuple{0, 0, 0, 0}, 1, 16, 32)}}
+	mesh.build(DefaultBVHOptions())
+	instance := &Instance{mesh: mesh, transform: Translate(0, 0, 0), material: &s.material}
+	rec = HitRecord{}
+	if !instance.hit(r, Epsilon, math.MaxFloat64, &rec) || rec.p.z > 0 {
+		t.Errorf("expected a hit on the far side of the instance, got %v", rec.p)
+	}
+}
+
+// TestStochasticCutout checks that rays pass through partly opaque surfaces with probability
+// 1 - opacity, that the same ray always sees the same surfaces with the same key, and that
+// keys of different samples change which surfaces it sees
+func TestStochasticCutout(t *testing.T) {
+	tri := Triangle{
+		position:  TrianglePosition{Tuple{-10, -10, 0, 0}, Tuple{10, -10, 0, 0}, Tuple{0, 10, 0, 0}},
+		normal:    Tuple{0, 0, 1, 0},
+		geometric: Tuple{0, 0, 1, 0},
+		material:  NewLambertian(NewConstant(Color{1, 1, 1})).WithOpacity(gray(0.3), 0),
+	}
+	rng := NewRNG(1, 0)
+	hits, n := 0, 10000
+	for i := 0; i < n; i++ {
+		r := Ray{Tuple{RandFloat(rng), RandFloat(rng), 1, 0}, Tuple{0, 0, -1, 0}}
+		var rec HitRecord
+		hit := tri.hit(r, Epsilon, math.MaxFloat64, &rec)
+		if hit {
+			hits++
+		}
+		if again := tri.hit(r, Epsilon, math.MaxFloat64, &rec); again != hit {
+			t.Fatalf("ray %v hit the surface %v, then %v", r, hit, again)
+		}
+	}
+	if fraction := float64(hits) / float64(n); math.Abs(fraction-0.3) > 0.02 {
+		t.Errorf("%g of rays hit a surface with opacity 0.3", fraction)
+	}
+
+	r := Ray{Tuple{0.5, 0.5, 1, 0}, Tuple{0, 0, -1, 0}}
+	hits = 0
+	for i := 0; i < n; i++ {
+		rec := HitRecord{key: mix64(uint64(i))}
+		if tri.hit(r, Epsilon, math.MaxFloat64, &rec) {
+			hits++
+		}
+	}
+	if fraction := float64(hits) / float64(n); math.Abs(fraction-0.3) > 0.02 {
+		t.Errorf("the same ray hit a surface with opacity 0.3 in %g of samples", fraction)
+	}
+}
+
+// TestLoadTextureAlpha checks that colors of images with alpha aren't premultiplied
+func TestLoadTextureAlpha(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+	img.Set(1, 0, color.NRGBA{0, 255, 0, 51})
+	colors, alpha := loadTexture(img)
+	if alpha == nil || alpha[0][0] != (Color{1, 1, 1}) || alpha[1][0] != (Color{0.2, 0.2, 0.2}) {
+		t.Errorf("got alpha %v", alpha)
+	}
+	if colors[1][0] != (Color{0, 1, 0}) {
+		t.Errorf("got color %v of a transparent pixel, expected (0, 1, 0)", colors[1][0])
+	}
+	img.Set(1, 0, color.NRGBA{0, 255, 0, 255})
+	if _, alpha := loadTexture(img); alpha != nil {
+		t.Errorf("got alpha of an opaque image")
+	}
+}
@@ -0,0 +1,379 @@
+package pt
+
+import (
+	"fmt"
+	"math"
+)
+
+// PrincipledParams are parameters of the Disney principled BSDF (Burley 2012, 2015). Every
+// parameter is a texture, scalar parameters use the red channel of gray textures. Light scattered
+// below the surface is followed by random walks instead of the diffusion profile of Burley 2015,
+// and transmission isn't absorbed inside the material
+type PrincipledParams struct {
+	BaseColor Texture
+	// Metallic blends between a dielectric and a conductor tinted by the base color
+	Metallic Texture
+	// Subsurface flattens the diffuse lobe like light scattered below the surface
+	Subsurface Texture
+	// Specular is the reflectance of dielectrics at normal incidence, 0.5 is 4%
+	Specular Texture
+	// SpecularTint tints specular reflection of dielectrics towards the base color
+	SpecularTint Texture
+	Roughness    Texture
+	// Anisotropic stretches highlights along the tangent, which is rotated around the normal by
+	// AnisotropicRotation, a fraction of a full turn
+	Anisotropic         Texture
+	AnisotropicRotation Texture
+	// Sheen is additional reflection at grazing angles, for cloth
+	Sheen     Texture
+	SheenTint Texture
+	// Clearcoat is the strength of a second, white specular lobe
+	Clearcoat Texture
+	// ClearcoatGloss sets roughness of the clearcoat from 0.1 to 0.001 (GTR1 alpha)
+	ClearcoatGloss Texture
+	// SpecTrans blends the dielectric towards a transmissive one with the index of refraction IOR
+	SpecTrans Texture
+	IOR       Texture
+	// Thin makes the surface a sheet with no inside where it's at least 0.5, like a leaf or paper:
+	// transmission isn't refracted and roughens with IOR, and diffuse light can pass through
+	Thin Texture
+	// DiffTrans is the fraction of diffuse light transmitted through thin surfaces
+	DiffTrans Texture
+	// Flatness flattens diffuse reflection of thin surfaces, which use it instead of Subsurface
+	Flatness Texture
+	// ScatterDistance is the mean free path of light below the surface for every channel, where it
+	// isn't zero light entering solid surfaces scatters inside them instead of the diffuse lobe and
+	// Subsurface is ignored. Objects have to be closed meshes or spheres, like for NewSubsurface
+	ScatterDistance Texture
+}
+
+// DefaultPrincipled returns parameters of a white diffuse dielectric with 4% specular reflection
+func DefaultPrincipled() PrincipledParams {
+	return PrincipledParams{
+		BaseColor:           gray(0.8),
+		Metallic:            gray(0),
+		Subsurface:          gray(0),
+		Specular:            gray(0.5),
+		SpecularTint:        gray(0),
+		Roughness:           gray(0.5),
+		Anisotropic:         gray(0),
+		AnisotropicRotation: gray(0),
+		Sheen:               gray(0),
+		SheenTint:           gray(0.5),
+		Clearcoat:           gray(0),
+		ClearcoatGloss:      gray(1),
+		SpecTrans:           gray(0),
+		IOR:                 gray(1.5),
+		Thin:                gray(0),
+		DiffTrans:           gray(0),
+		Flatness:            gray(0),
+		ScatterDistance:     gray(0),
+	}
+}
+
+// NewPrincipled returns the Disney principled material
+func NewPrincipled(p PrincipledParams) Material {
+	return Material{material: Principled, albedo: p.BaseColor, principled: &p}
+}
+
+// textures returns pointers to all parameters, so they can be changed together
+func (p *PrincipledParams) textures() []*Texture {
+	return []*Texture{&p.BaseColor, &p.Metallic, &p.Subsurface, &p.Specular, &p.SpecularTint, &p.Roughness,
+		&p.Anisotropic, &p.AnisotropicRotation, &p.Sheen, &p.SheenTint, &p.Clearcoat, &p.ClearcoatGloss, &p.SpecTrans, &p.IOR,
+		&p.Thin, &p.DiffTrans, &p.Flatness, &p.ScatterDistance}
+}
+
+func (p *PrincipledParams) String() string {
+	return fmt.Sprintf("base color %v, metallic %v, subsurface %v, specular %v (tint %v), roughness %v, anisotropic %v (rotation %v), sheen %v (tint %v), clearcoat %v (gloss %v), transmission %v, ior %v, thin %v, diffuse transmission %v, flatness %v, scatter distance %v",
+		p.BaseColor, p.Metallic, p.Subsurface, p.Specular, p.SpecularTint, p.Roughness,
+		p.Anisotropic, p.AnisotropicRotation, p.Sheen, p.SheenTint, p.Clearcoat, p.ClearcoatGloss, p.SpecTrans, p.IOR,
+		p.Thin, p.DiffTrans, p.Flatness, p.ScatterDistance)
+}
+
+// principled holds parameters of a principled material at a hit point
+type principled struct {
+	baseColor                                                Color
+	metallic, subsurface, specular, specularTint, roughness  float64
+	anisotropic, sheen, sheenTint, clearcoat, clearcoatGloss float64
+	specTrans, ior, diffTrans, flatness                      float64
+	thin                                                     bool
+	scatterDistance                                          Color
+}
+
+func (p *PrincipledParams) at(rec HitRecord) principled {
+	return principled{
+		p.BaseColor.color(rec),
+		p.Metallic.value(rec), p.Subsurface.value(rec), p.Specular.value(rec), p.SpecularTint.value(rec), p.Roughness.value(rec),
+		p.Anisotropic.value(rec), p.Sheen.value(rec), p.SheenTint.value(rec), p.Clearcoat.value(rec), p.ClearcoatGloss.value(rec),
+		p.SpecTrans.value(rec), p.IOR.value(rec), p.DiffTrans.value(rec), p.Flatness.value(rec),
+		p.Thin.value(rec) >= 0.5,
+		p.ScatterDistance.color(rec),
+	}
+}
+
+// distribution returns the anisotropic GGX distribution of the specular lobes
+func (p principled) distribution() microfacet {
+	return anisotropicGGX(p.roughness, p.anisotropic)
+}
+
+// transmissionDistribution returns the distribution of the transmission lobe, light passing through
+// both sides of thin surfaces is spread more by higher indices of refraction (Burley 2015)
+func (p principled) transmissionDistribution() microfacet {
+	if !p.thin {
+		return p.distribution()
+	}
+	return anisotropicGGX(math.Max(0, math.Min(1, (0.65*p.ior-0.35)*p.roughness)), p.anisotropic)
+}
+
+// tint returns the base color normalized to luminance 1, the hue of tinted lobes
+func (p principled) tint() Color {
+	if l := p.baseColor.Luminance(); l > 0 {
+		return p.baseColor.DivScalar(l)
+	}
+	return Color{1, 1, 1}
+}
+
+// specularColor returns reflectance of the specular lobe at normal incidence
+func (p principled) specularColor() Color {
+	dielectric := mix(Color{1, 1, 1}, p.tint(), p.specularTint).MulScalar(0.08 * p.specular)
+	return mix(dielectric, p.baseColor, p.metallic)
+}
+
+// principledLobes are weights or sampling probabilities of lobes of a principled material,
+// sheen is sampled with the diffuse lobe
+type principledLobes struct {
+	diffuse, sheen, diffTrans, specular, transmission, subsurface, clearcoat float64
+}
+
+func (l principledLobes) total() float64 {
+	return l.diffuse + l.sheen + l.diffTrans + l.specular + l.transmission + l.subsurface + l.clearcoat
+}
+
+// weights returns weights of the lobes. Light transmitted by the dielectric, or scattered below
+// its surface, is also reflected by it, so the specular lobe only covers the rest
+func (p principled) weights() principledLobes {
+	var w principledLobes
+	w.transmission = (1 - p.metallic) * p.specTrans
+	w.sheen = (1 - p.metallic) * (1 - p.specTrans)
+	switch {
+	case p.thin:
+		w.diffuse, w.diffTrans = w.sheen*(1-p.diffTrans), w.sheen*p.diffTrans
+	case p.scatterDistance != (Color{0, 0, 0}):
+		w.subsurface = w.sheen
+	default:
+		w.diffuse = w.sheen
+	}
+	w.specular = 1 - w.transmission - w.subsurface
+	w.clearcoat = 0.25 * p.clearcoat
+	return w
+}
+
+// clearcoatAlpha returns alpha of the GTR1 distribution of the clearcoat
+func (p principled) clearcoatAlpha() float64 {
+	return 0.1 + (0.001-0.1)*p.clearcoatGloss
+}
+
+// lobes returns probabilities of sampling the lobes for light leaving at cosine cosO with the
+// normal, roughly in proportion to their reflectance
+func (p principled) lobes(cosO float64) principledLobes {
+	l := p.weights()
+	fresnel := schlickWeight(cosO)
+	l.diffuse = l.diffuse*p.baseColor.Luminance() + l.sheen*p.sheen
+	l.sheen = 0
+	l.diffTrans *= p.baseColor.Luminance()
+	l.specular *= mix(p.specularColor(), Color{1, 1, 1}, fresnel).Luminance()
+	l.clearcoat *= 0.04 + 0.96*fresnel
+	total := l.total()
+	if total <= 0 {
+		return principledLobes{}
+	}
+	return principledLobes{l.diffuse / total, 0, l.diffTrans / total, l.specular / total,
+		l.transmission / total, l.subsurface / total, l.clearcoat / total}
+}
+
+// evalPrincipled evaluates the principled BSDF in the local shading frame
+func evalPrincipled(p principled, wo, wi Tuple) Color {
+	w := p.weights()
+	d := p.distribution()
+	f := Color{0, 0, 0}
+
+	if w.transmission > 0 {
+		var reflection, transmission Color
+		if p.thin {
+			r, t := thinDielectric(p.transmissionDistribution(), wo, wi, p.ior)
+			reflection, transmission = Color{r, r, r}, Color{t, t, t}
+		} else {
+			reflection, transmission = roughDielectric(d, wo, wi, p.ior, thinFilm{})
+		}
+		f = p.baseColor.sqrt().Mul(transmission).Add(reflection).MulScalar(w.transmission)
+	}
+	if w.subsurface > 0 {
+		// the surface of the scattering material is a white dielectric
+		reflection, transmission := roughDielectric(d, wo, wi, p.ior, thinFilm{})
+		f = f.Add(reflection.Add(transmission).MulScalar(w.subsurface))
+	}
+	if w.diffTrans > 0 && wo.z*wi.z < 0 {
+		f = f.Add(p.baseColor.MulScalar(w.diffTrans * math.Abs(wi.z) / math.Pi))
+	}
+
+	if wo.z < 0 {
+		wo.z, wi.z = -wo.z, -wi.z
+	}
+	if wi.z <= 0 {
+		return f
+	}
+	h := wo.Add(wi).Normalize()
+	cosD := wi.Dot(h)
+	fresnelO, fresnelI, fresnelD := schlickWeight(wo.z), schlickWeight(wi.z), schlickWeight(cosD)
+
+	if w.sheen > 0 {
+		// diffuse retro-reflection at grazing angles, blended with a flatter approximation
+		// of subsurface scattering (Hanrahan-Krueger)
+		flatness := p.subsurface
+		if p.thin {
+			flatness = p.flatness
+		}
+		fd90 := 0.5 + 2*cosD*cosD*p.roughness
+		fd := (1 + (fd90-1)*fresnelI) * (1 + (fd90-1)*fresnelO)
+		fss90 := cosD * cosD * p.roughness
+		fss := (1 + (fss90-1)*fresnelI) * (1 + (fss90-1)*fresnelO)
+		ss := 1.25 * (fss*(1/(wi.z+wo.z)-0.5) + 0.5)
+		diffuse := p.baseColor.MulScalar(w.diffuse * (fd + (ss-fd)*flatness) / math.Pi)
+		sheen := mix(Color{1, 1, 1}, p.tint(), p.sheenTint).MulScalar(w.sheen * p.sheen * fresnelD)
+		f = f.Add(diffuse.Add(sheen).MulScalar(wi.z))
+	}
+	if w.specular > 0 {
+		fresnel := mix(p.specularColor(), Color{1, 1, 1}, fresnelD)
+		f = f.Add(fresnel.MulScalar(w.specular * microfacetReflection(d, wo, wi)))
+	}
+	if w.clearcoat > 0 {
+		alpha := p.clearcoatAlpha()
+		c := w.clearcoat * (0.04 + 0.96*fresnelD) * gtr1(h.z, alpha) * isotropic(0.25).G(wo, wi) / (4 * wo.z)
+		f = f.Add(Color{c, c, c})
+	}
+	return f
+}
+
+// pdfPrincipled returns the probability density of sampling wi in the local shading frame
+func pdfPrincipled(p principled, wo, wi Tuple) float64 {
+	l := p.lobes(math.Abs(wo.z))
+	d := p.distribution()
+	pdf := 0.0
+	if l.transmission > 0 {
+		if p.thin {
+			pdf = l.transmission * thinDielectricPdf(p.transmissionDistribution(), wo, wi, p.ior)
+		} else {
+			pdf = l.transmission * roughDielectricPdf(d, wo, wi, p.ior, thinFilm{})
+		}
+	}
+	if l.subsurface > 0 {
+		pdf += l.subsurface * roughDielectricPdf(d, wo, wi, p.ior, thinFilm{})
+	}
+	if l.diffTrans > 0 && wo.z*wi.z < 0 {
+		pdf += l.diffTrans * math.Abs(wi.z) / math.Pi
+	}
+	if wo.z < 0 {
+		wo.z, wi.z = -wo.z, -wi.z
+	}
+	if wi.z <= 0 {
+		return pdf
+	}
+	pdf += l.specular * microfacetReflectionPdf(d, wo, wi)
+	if l.clearcoat > 0 {
+		h := wo.Add(wi).Normalize()
+		pdf += l.clearcoat * gtr1(h.z, p.clearcoatAlpha()) * h.z / (4 * wo.Dot(h))
+	}
+	return pdf + l.diffuse*wi.z/math.Pi
+}
+
+// scatterPrincipled samples a lobe of the principled BSDF
+func (m Material) scatterPrincipled(rec HitRecord, frame ONB, wo Tuple, sampler Sampler, srec *ScatterRecord) bool {
+	p := m.principled.at(rec)
+	side := 1.0
+	if wo.z < 0 {
+		side = -1
+	}
+	above := Tuple{wo.x, wo.y, wo.z * side, 0}
+	l := p.lobes(above.z)
+	if l.total() == 0 {
+		return false
+	}
+	w := p.weights()
+
+	lobe := sampler.Get1D()
+	u, v := sampler.Get2D()
+	d := p.distribution()
+	var wi Tuple
+	switch {
+	case lobe < l.diffuse:
+		wi = Tuple{0, 0, 1, 0}.Add(sampleSphere(u, v)).Normalize()
+	case lobe < l.diffuse+l.diffTrans:
+		wi = Tuple{0, 0, 1, 0}.Add(sampleSphere(u, v)).Normalize()
+		wi.z = -wi.z
+	case lobe < l.diffuse+l.diffTrans+l.specular:
+		if d.smooth() {
+			fresnel := mix(p.specularColor(), Color{1, 1, 1}, schlickWeight(above.z))
+			return m.scatterSpecular(rec, frame, Tuple{-wo.x, -wo.y, wo.z, 0}, fresnel.MulScalar(w.specular/l.specular), srec)
+		}
+		wi = reflect(above, d.sample(above, u, v))
+	case lobe < l.diffuse+l.diffTrans+l.specular+l.transmission:
+		var refracted Tuple
+		var reflected, ok bool
+		if p.thin {
+			d = p.transmissionDistribution()
+			refracted, reflected, ok = sampleThinDielectric(d, wo, p.ior, u, v, sampler.Get1D())
+		} else {
+			refracted, _, reflected, ok = sampleDielectric(d, wo, p.ior, thinFilm{}, u, v, sampler.Get1D())
+		}
+		switch {
+		case !ok:
+			return false
+		case !d.smooth():
+			return m.scatterSampled(rec, frame, wo, refracted, srec)
+		case reflected:
+			return m.scatterSpecular(rec, frame, refracted, Color{1, 1, 1}.MulScalar(w.transmission/l.transmission), srec)
+		}
+		return m.scatterSpecular(rec, frame, refracted, p.baseColor.sqrt().MulScalar(w.transmission/l.transmission), srec)
+	case lobe < l.diffuse+l.diffTrans+l.specular+l.transmission+l.subsurface:
+		// light refracted in or reflected back inside by the surface is scattered below it by randomWalk
+		refracted, _, _, ok := sampleDielectric(d, wo, p.ior, thinFilm{}, u, v, sampler.Get1D())
+		switch {
+		case !ok:
+			return false
+		case !d.smooth():
+			ok = m.scatterSampled(rec, frame, wo, refracted, srec)
+		default:
+			ok = m.scatterSpecular(rec, frame, refracted, Color{1, 1, 1}.MulScalar(w.subsurface/l.subsurface), srec)
+		}
+		srec.subsurface = ok && srec.ray.direction.Dot(rec.normal) < 0
+		return ok
+	default:
+		alpha := p.clearcoatAlpha()
+		cosH := math.Sqrt(math.Max(0, (1-math.Pow(alpha*alpha, 1-u))/(1-alpha*alpha)))
+		sinH := math.Sqrt(1 - cosH*cosH)
+		h := Tuple{sinH * math.Cos(2*math.Pi*v), sinH * math.Sin(2*math.Pi*v), cosH, 0}
+		wi = reflect(above, h)
+	}
+	wi.z *= side
+	return m.scatterSampled(rec, frame, wo, wi, srec)
+}
+
+// gtr1 is the generalized Trowbridge-Reitz distribution with exponent 1 used by the clearcoat,
+// it has a longer tail than GGX
+func gtr1(cosH, alpha float64) float64 {
+	if cosH <= 0 {
+		return 0
+	}
+	a2 := alpha * alpha
+	return (a2 - 1) / (math.Pi * math.Log(a2) * (1 + (a2-1)*cosH*cosH))
+}
+
+// schlickWeight is the weight of white in Schlick's approximation of Fresnel reflectance
+func schlickWeight(cos float64) float64 {
+	return math.Pow(math.Max(0, 1-cos), 5)
+}
+
+// mix returns linear interpolation between colors a and b
+func mix(a, b Color, t float64) Color {
+	return a.MulScalar(1 - t).Add(b.MulScalar(t))
+}
@@ -0,0 +1,63 @@
+package pt
+
+import (
+	"math"
+)
+
+// RandFloat returns a random value in [0, 1)
+func RandFloat(rng *RNG) float64 {
+	return rng.Float64()
+}
+
+// RandFloatRange returns a random value in [min, max)
+func RandFloatRange(min, max float64, rng *RNG) float64 {
+	return min + rng.Float64()*(max-min)
+}
+
+// RandInUnitSphere returns a random unit vector
+func RandInUnitSphere(rng *RNG) Tuple {
+	p := Tuple{0, 0, 0, 0}
+	for {
+		p = (Tuple{RandFloat(rng), RandFloat(rng), RandFloat(rng), 1}.Subtract(Tuple{0.5, 0.5, 0.5, 0})).MulScalar(2.0)
+		if p.Magnitude()*p.Magnitude() < 1.0 {
+			break
+		}
+	}
+	return p.Normalize()
+}
+
+// RandInUnitHemisphere returns a random vector in the hemisphere around normal
+func RandInUnitHemisphere(rng *RNG, normal Tuple) Tuple {
+	p := Tuple{0, 0, 0, 0}
+	for {
+		p = (Tuple{RandFloat(rng), RandFloat(rng), RandFloat(rng), 1}.Subtract(Tuple{0.5, 0.5, 0.5, 0})).MulScalar(2.0)
+		if p.Dot(normal)/(p.Magnitude()*normal.Magnitude()) >= 0.0 {
+			break
+		}
+	}
+	return p
+}
+
+// sampleDisk maps a uniform 2D sample to a point in the unit disk, keeping the stratification
+// of samples (Shirley and Chiu concentric mapping)
+func sampleDisk(u, v float64) Tuple {
+	a, b := 2*u-1, 2*v-1
+	if a == 0 && b == 0 {
+		return Tuple{0, 0, 0, 0}
+	}
+	var r, phi float64
+	if math.Abs(a) > math.Abs(b) {
+		r, phi = a, math.Pi/4*(b/a)
+	} else {
+		r, phi = b, math.Pi/2-math.Pi/4*(a/b)
+	}
+	return Tuple{r * math.Cos(phi), r * math.Sin(phi), 0, 0}
+}
+
+// sampleSphere maps a uniform 2D sample to a point on the unit sphere
+func sampleSphere(u, v float64) Tuple {
+	z := 1 - 2*u
+	r := math.Sqrt(math.Max(0, 1-z*z))
+	phi := 2 * math.Pi * v
+	return Tuple{r * math.Cos(phi), r * math.Sin(phi), z, 0}
+}
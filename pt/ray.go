@@ -1,4 +1,4 @@
-package main
+package pt
 
 // Ray struct represents a ray with origin and a direction
 type Ray struct {
@@ -0,0 +1,360 @@
+package pt
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Options holds settings used by Renderer
+type Options struct {
+	Width, Height  int
+	Samples, Depth int
+	Preview        bool
+	Jitter         bool
+	// Sampler is the name of the sampler, see NewSampler, DefaultSampler if empty
+	Sampler string
+	// Seed selects random numbers used by the render, renders with the same seed and
+	// options are identical regardless of the number of workers and of the machine
+	Seed int64
+}
+
+// Settings holds render options and output settings of a scene file
+type Settings struct {
+	Options
+	ToneMapping bool
+	Output      string
+	Format      string
+}
+
+// DefaultSettings returns settings used for values missing in a scene file
+func DefaultSettings() Settings {
+	return Settings{
+		Options: Options{
+			Width:   512,
+			Height:  384,
+			Samples: 64,
+			Depth:   8,
+			Preview: false,
+			Jitter:  true,
+			Sampler: DefaultSampler,
+		},
+		ToneMapping: true,
+		Output:      fmt.Sprintf("frame_%d", time.Now().UnixNano()/1e6),
+		Format:      "png16",
+	}
+}
+
+func (opts Options) validate() error {
+	if opts.Width <= 0 || opts.Height <= 0 {
+		return fmt.Errorf("image size has to be positive, got %dx%d", opts.Width, opts.Height)
+	}
+	if opts.Samples <= 0 {
+		return fmt.Errorf("samples has to be positive, got %d", opts.Samples)
+	}
+	if opts.Depth < 0 {
+		return fmt.Errorf("depth can't be negative, got %d", opts.Depth)
+	}
+	if _, err := NewSampler(opts.Sampler, opts.Samples, opts.Seed); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (settings Settings) validate() error {
+	if err := settings.Options.validate(); err != nil {
+		return err
+	}
+	if _, _, err := imageFormat(settings.Format); err != nil {
+		return err
+	}
+	return nil
+}
+
+func imageFormat(format string) (int, int, error) {
+	switch format {
+	case "png8":
+		return PNG, 8, nil
+	case "png16":
+		return PNG, 16, nil
+	case "ppm":
+		return PPM, 8, nil
+	}
+	return 0, 0, fmt.Errorf("unknown format %q (expected png8, png16 or ppm)", format)
+}
+
+// Progress is reported by Renderer after every rendered sample pass
+type Progress struct {
+	Done, Total int
+	// FrameTime is time it took to render the last pass, ETA is estimated time until the end
+	FrameTime, ETA time.Duration
+}
+
+// Renderer renders scenes using multiple goroutines
+type Renderer struct {
+	// Workers is the number of goroutines used for rendering, runtime.NumCPU() if zero
+	Workers int
+	// Progress, if not nil, is called after every sample pass
+	Progress func(Progress)
+}
+
+// Film holds the rendered image
+type Film struct {
+	width, height int
+	pixels        []Color
+}
+
+// Width returns width of the image
+func (f *Film) Width() int {
+	return f.width
+}
+
+// Height returns height of the image
+func (f *Film) Height() int {
+	return f.height
+}
+
+// At returns color of the pixel, (0, 0) is the top-left corner
+func (f *Film) At(x, y int) Color {
+	return f.pixels[(f.height-1-y)*f.width+x]
+}
+
+// Save saves the image in png8, png16 or ppm format, extension is added to path if it's missing
+func (f *Film) Save(path, format string, toneMapping bool) error {
+	extension, bitDepth, err := imageFormat(format)
+	if err != nil {
+		return err
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); (ext == ".png" && extension == PNG) || (ext == ".ppm" && extension == PPM) {
+		path = strings.TrimSuffix(path, filepath.Ext(path))
+	}
+
+	// SaveImage applies tone mapping in place
+	canvas := make([]Color, len(f.pixels))
+	copy(canvas, f.pixels)
+	return SaveImage(canvas, f.width, f.height, 255, path, extension, bitDepth, toneMapping)
+}
+
+// Render renders the scene, it returns early with ctx.Err() if the context is cancelled
+func (r *Renderer) Render(ctx context.Context, scene *Scene, opts Options) (*Film, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if !scene.hasCamera {
+		return nil, fmt.Errorf("scene has no camera")
+	}
+
+	scene.buildWorld()
+
+	hsize, vsize, samples := opts.Width, opts.Height, opts.Samples
+	camera := scene.camera
+
+	workers := r.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if vsize < workers {
+		workers = vsize
+	}
+
+	// every sample pass renders the whole frame with one sample per pixel, rows of a pass
+	// are shared between workers and samples are added to the canvas in order of passes,
+	// so the result doesn't depend on the number of workers
+	canvas := make([]Color, vsize*hsize)
+	for pass := 0; pass < samples; pass++ {
+		start := time.Now()
+		var nextRow int64 = -1
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sampler, _ := NewSampler(opts.Sampler, samples, opts.Seed)
+				for ctx.Err() == nil {
+					y := int(atomic.AddInt64(&nextRow, 1))
+					if y >= vsize {
+						return
+					}
+					for x := 0; x < hsize; x++ {
+						sampler.StartPixelSample(y*hsize+x, pass)
+						sampler.SetDimension(pixelDimension)
+						u := float64(x)
+						v := float64(y)
+						if opts.Jitter {
+							du, dv := sampler.Get2D()
+							u += du
+							v += dv
+						}
+						u /= float64(hsize)
+						v /= float64(vsize)
+						sampler.SetDimension(lensDimension)
+						r := camera.getRay(u, v, sampler)
+
+						col := colorize(r, scene, &opts, 0, 0, sampler, nil)
+
+						canvas[y*hsize+x] = canvas[y*hsize+x].Add(col)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if r.Progress != nil {
+			frameTime := time.Since(start)
+			r.Progress(Progress{pass + 1, samples, frameTime, frameTime * time.Duration(samples-pass-1)})
+		}
+	}
+
+	for y := 0; y < vsize; y++ {
+		for x := 0; x < hsize; x++ {
+			canvas[y*hsize+x] = canvas[y*hsize+x].DivScalar(float64(samples))
+		}
+	}
+
+	return &Film{hsize, vsize, canvas}, nil
+}
+
+// colorize returns radiance arriving along the ray. pdf is the probability density of sampling
+// the ray from the BSDF at the previous bounce, emission of lights it hits is weighted against
+// sampling lights there. It's zero for camera rays and rays of specular lobes, which can't be
+// sampled by lights, so their emission is added fully. inside are dielectrics containing the origin
+func colorize(r Ray, scene *Scene, opts *Options, d int, pdf float64, sampler Sampler, inside media) Color {
+	world, envMap := &scene.world, scene.envMap
+	depth := opts.Depth
+	// the key makes stochastic cutouts along the ray and mixes at the hit differ between samples
+	sampler.SetDimension(bounceStart(d) + hitDimension)
+	rec := HitRecord{key: uint64(sampler.Get1D() * (1 << 53))}
+	if world.hit(r, Epsilon, math.MaxFloat64, &rec) {
+		// only emission materials of the surfaces themselves are sampled as lights, not ones in mixes
+		light := rec.material.material == Emission
+		// the hit keeps the material chosen by mixes, so light sampling evaluates the one Scatter samples
+		rec.material = rec.material.pick(rec, r.direction.Normalize().Negate()).at(rec)
+		var srec ScatterRecord
+		sampler.SetDimension(bounceStart(d) + bsdfDimension)
+		if !opts.Preview {
+			if d >= depth {
+				return Color{0, 0, 0}
+			}
+			// light is absorbed on the way to the ray's origin
+			transmittance := inside.current().transmittance(rec.t * r.direction.Magnitude())
+			if rec.material.material == Emission {
+				emitted := rec.material.albedo.color(rec).Mul(transmittance)
+				if pdf == 0 || !light {
+					return emitted
+				}
+				return emitted.MulScalar(powerHeuristic(pdf, scene.lights.pdf(r.origin, &rec)))
+			}
+			return shade(r, rec, scene, opts, d, pdf, sampler, inside).Mul(transmittance)
+		} else {
+			if d < depth && (rec.material.material == Emission || rec.material.Scatter(r, rec, sampler, &srec)) {
+				if rec.material.metalicity > 0.0 {
+					return rec.material.albedo.color(rec).Mul(colorize(srec.ray, scene, opts, d+1, 0, sampler, nil))
+				} else if rec.material.transmission > 0.0 {
+					return rec.material.albedo.color(rec).Mul(colorize(srec.ray, scene, opts, d+1, 0, sampler, nil))
+				} else {
+					shadeAmount := Tuple{0, 1, 0, 0}.Dot(rec.normal)
+					shadowMin := 0.5
+					return rec.material.albedo.color(rec).MulScalar(shadeAmount*(1-shadowMin) + shadowMin)
+				}
+			} else {
+				return Color{0, 0, 0}
+			}
+		}
+	} else {
+		if len(world.atm) == 1 {
+			d := r.direction.Normalize()
+			rec.uT = 0.5 - (math.Atan2(d.z, d.x))/(2*math.Pi)*-1
+			rec.vT = 0.5 + (math.Asin(d.y))/(math.Pi)*-1
+			rec.p = d
+
+			color := world.atm[0].ComputeIncidentLight(Tuple{0, world.atm[0].earthRadius + 1, 0, 0}, rec.p, 0, math.MaxFloat64)
+			return Color{color.x, color.y, color.z}
+		} else if envMap.mode == SphereImageUV {
+			d := r.direction.Normalize()
+			rec.uT = 0.5 - (math.Atan2(d.z, d.x))/(2*math.Pi)*-1
+			rec.vT = 0.5 + (math.Asin(d.y))/(math.Pi)*-1
+		}
+		return envMap.color(rec)
+	}
+}
+
+// shade returns radiance scattered at the hit along the ray, pdf is the density of sampling the ray like in colorize
+func shade(r Ray, rec HitRecord, scene *Scene, opts *Options, d int, pdf float64, sampler Sampler, inside media) Color {
+	back := inside
+	if rec.material.dielectric() {
+		var outer medium
+		var ignored bool
+		back, outer, ignored = inside.cross(r, rec)
+		if ignored {
+			return colorize(Ray{rec.p, r.direction}, scene, opts, d, pdf, sampler, back)
+		}
+		// refraction depends on indices of refraction on both sides
+		rec.material.ior /= outer.ior
+	}
+	var srec ScatterRecord
+	if !rec.material.Scatter(r, rec, sampler, &srec) {
+		return Color{0, 0, 0}
+	}
+	transmitted := srec.ray.direction.Dot(rec.normal)*r.direction.Dot(rec.normal) > 0
+	next := inside
+	if transmitted {
+		next = back
+	}
+
+	// lights are sampled if the next bounce could still add their emission, also when
+	// a specular lobe was sampled, since other lobes can reflect the light
+	direct := Color{0, 0, 0}
+	if len(scene.lights.lights) > 0 && d+1 < opts.Depth {
+		sampler.SetDimension(bounceStart(d) + lightDimension)
+		direct = scene.directLight(r, rec, sampler, inside.current(), back.current())
+	}
+
+	if srec.subsurface {
+		// light going into the material reaches its boundary elsewhere after a random walk, where
+		// it's shaded like any hit from inside, so it's refracted out or reflected back inside
+		sampler.SetDimension(bounceStart(d) + walkDimension)
+		in, exit, throughput, ok := scene.randomWalk(srec.ray, rec, sampler)
+		if !ok || d+1 >= opts.Depth {
+			return direct
+		}
+		sampler.SetDimension(bounceStart(d+1) + bsdfDimension)
+		radiance := shade(in, exit, scene, opts, d+1, 0, sampler, inside)
+		return direct.Add(srec.attenuation.Mul(throughput).Mul(radiance))
+	}
+
+	nextPdf := srec.pdf
+	if srec.specular {
+		nextPdf = 0
+	}
+	return direct.Add(srec.attenuation.Mul(colorize(srec.ray, scene, opts, d+1, nextPdf, sampler, next)))
+}
+
+// directLight samples a light from the hit point and returns radiance it reflects along the ray,
+// weighted against sampling the same direction from the BSDF. front is the medium on the side of the ray,
+// back is the one behind the surface
+func (scene *Scene) directLight(r Ray, rec HitRecord, sampler Sampler, front, back medium) Color {
+	wi, dist, emitted, lightPdf := scene.sampleLight(rec.p, sampler)
+	if lightPdf <= 0 {
+		return Color{0, 0, 0}
+	}
+	wo := r.direction.Normalize().Negate()
+	f := rec.material.Eval(rec, wo, wi)
+	if f == (Color{0, 0, 0}) || scene.occluded(rec.p, wi, dist, rec.key) {
+		return Color{0, 0, 0}
+	}
+	weight := powerHeuristic(lightPdf, rec.material.Pdf(rec, wo, wi))
+	if wi.Dot(rec.normal)*wo.Dot(rec.normal) < 0 {
+		front = back
+	}
+	return f.Mul(emitted).Mul(front.transmittance(dist)).MulScalar(weight / lightPdf)
+}
@@ -0,0 +1,73 @@
+package pt
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"testing"
+)
+
+// TestRenderDeterministic checks that renders don't depend on the number of workers
+// and that different seeds give different renders
+func TestRenderDeterministic(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	opts := Options{Width: 24, Height: 16, Samples: 4, Depth: 4, Jitter: true, Seed: 7}
+	render := func(workers int, seed int64) *Film {
+		renderer := Renderer{Workers: workers}
+		o := opts
+		o.Seed = seed
+		film, err := renderer.Render(context.Background(), materialsScene(), o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return film
+	}
+
+	reference := render(1, opts.Seed)
+	for _, workers := range []int{2, 5} {
+		film := render(workers, opts.Seed)
+		for i := range film.pixels {
+			if film.pixels[i] != reference.pixels[i] {
+				t.Fatalf("%d workers: pixel %d is %v, expected %v", workers, i, film.pixels[i], reference.pixels[i])
+			}
+		}
+	}
+
+	film := render(2, opts.Seed+1)
+	same := true
+	for i := range film.pixels {
+		if film.pixels[i] != reference.pixels[i] {
+			same = false
+			break
+		}
+	}
+	if same {
+		t.Error("renders with different seeds are identical")
+	}
+}
+
+func TestRNG(t *testing.T) {
+	a, b := NewRNG(1, 0), NewRNG(1, 1)
+	same := 0
+	sum := 0.0
+	const n = 10000
+	for i := 0; i < n; i++ {
+		x, y := a.Float64(), b.Float64()
+		if x < 0 || x >= 1 {
+			t.Fatalf("Float64 returned %v", x)
+		}
+		if x == y {
+			same++
+		}
+		sum += x
+	}
+	if same > 0 {
+		t.Errorf("streams 0 and 1 returned %d equal values", same)
+	}
+	if mean := sum / n; mean < 0.49 || mean > 0.51 {
+		t.Errorf("mean of uniform values is %v", mean)
+	}
+}
@@ -0,0 +1,58 @@
+package pt
+
+// RNG is a PCG32 random number generator. Every sample of every pixel gets its own
+// generator derived from the seed of the render, so renders don't depend on the number
+// of goroutines or on timing and can be repeated exactly
+type RNG struct {
+	state, inc uint64
+}
+
+// NewRNG returns a generator of the given stream seeded with seed, different streams
+// are independent even with the same seed
+func NewRNG(seed, stream uint64) *RNG {
+	r := &RNG{}
+	r.seed(seed, stream)
+	return r
+}
+
+func (r *RNG) seed(seed, stream uint64) {
+	r.state = 0
+	r.inc = stream<<1 | 1
+	r.Uint32()
+	r.state += seed
+	r.Uint32()
+}
+
+// Uint32 returns a uniformly distributed 32-bit value
+func (r *RNG) Uint32() uint32 {
+	old := r.state
+	r.state = old*6364136223846793005 + r.inc
+	xorShifted := uint32(((old >> 18) ^ old) >> 27)
+	rot := uint32(old >> 59)
+	return xorShifted>>rot | xorShifted<<((-rot)&31)
+}
+
+// Float64 returns a uniformly distributed value in [0, 1) with 53 random bits
+func (r *RNG) Float64() float64 {
+	a := uint64(r.Uint32()) >> 5
+	b := uint64(r.Uint32()) >> 6
+	return float64(a<<26|b) / (1 << 53)
+}
+
+// mix64 is the finalizer of SplitMix64, it scrambles bits of x so that
+// similar inputs give unrelated outputs
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// pixelRNG sets up the generator for a sample of a pixel, both the state and the stream are
+// hashed because PCG streams started from the same state are correlated
+func pixelRNG(r *RNG, seed int64, pixel, sample int) {
+	key := mix64(uint64(seed) ^ mix64(mix64(uint64(pixel))+uint64(sample)))
+	r.seed(key, mix64(key+0x9e3779b97f4a7c15))
+}
@@ -0,0 +1,329 @@
+package pt
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Sampler supplies sample values in [0, 1) for a sample of a pixel. Values are drawn from
+// dimensions of a sequence, every call takes the next dimensions, so the same dimensions
+// are used for the same purpose (position in the pixel, on the lens...) in every sample.
+// Samplers aren't safe for concurrent use, every goroutine needs its own one
+type Sampler interface {
+	// StartPixelSample prepares the sampler for a sample of a pixel and resets the dimension
+	StartPixelSample(pixel, sample int)
+	// SetDimension selects the dimension used by the next call
+	SetDimension(dimension int)
+	// Get1D returns a value of the next dimension
+	Get1D() float64
+	// Get2D returns values of the next two dimensions, they are stratified together
+	Get2D() (float64, float64)
+}
+
+// Layout of sample dimensions. Every bounce starts at a fixed dimension, the first
+// dimensions of a bounce are used for sampling lights, then the BSDF, the seed of random
+// walks and the last one for the key of the hit
+const (
+	pixelDimension       = 0 // 2D position inside the pixel
+	lensDimension        = 2 // 2D position on the lens
+	firstBounceDimension = 4
+	lightDimension       = 0  // 1D choice of a light and 2D position on it
+	bsdfDimension        = 3  // choices of lobes and 2D directions, up to 9 dimensions
+	walkDimension        = 12 // 1D seed of random walks below the surface
+	hitDimension         = 13 // 1D key of stochastic cutouts along the ray and mixes at the hit
+	bounceDimensions     = 14
+)
+
+// bounceStart returns the first dimension of a bounce
+func bounceStart(depth int) int {
+	return firstBounceDimension + depth*bounceDimensions
+}
+
+// DefaultSampler is used if Options.Sampler is empty
+const DefaultSampler = "sobol"
+
+// NewSampler returns a sampler by name: independent, stratified, halton or sobol. samples is
+// the number of samples per pixel, random numbers are derived from seed like in Options
+func NewSampler(name string, samples int, seed int64) (Sampler, error) {
+	switch name {
+	case "independent":
+		return &independentSampler{seed: seed}, nil
+	case "stratified":
+		return newStratifiedSampler(samples, seed), nil
+	case "halton":
+		return &haltonSampler{seed: seed}, nil
+	case "sobol", "":
+		return &sobolSampler{seed: seed}, nil
+	}
+	return nil, fmt.Errorf("unknown sampler %q (expected independent, stratified, halton or sobol)", name)
+}
+
+// hashSample returns a hash of the seed, pixel and dimension of a sample
+func hashSample(seed int64, pixel, dimension int) uint64 {
+	return mix64(uint64(seed) ^ mix64(mix64(uint64(pixel))+uint64(dimension)))
+}
+
+// independentSampler returns uniform random values without any stratification
+type independentSampler struct {
+	seed int64
+	rng  RNG
+}
+
+func (s *independentSampler) StartPixelSample(pixel, sample int) {
+	pixelRNG(&s.rng, s.seed, pixel, sample)
+}
+
+// SetDimension does nothing, values of independent dimensions don't depend on their order
+func (s *independentSampler) SetDimension(dimension int) {}
+
+func (s *independentSampler) Get1D() float64 {
+	return s.rng.Float64()
+}
+
+func (s *independentSampler) Get2D() (float64, float64) {
+	return s.rng.Float64(), s.rng.Float64()
+}
+
+// stratifiedSampler uses correlated multi-jittered sampling (Kensler 2013), samples of a pixel
+// are jittered in cells of a grid and also stratified along both axes, for any number of samples
+type stratifiedSampler struct {
+	samples, m, n int
+	seed          int64
+	pixel, sample int
+	dimension     int
+}
+
+func newStratifiedSampler(samples int, seed int64) *stratifiedSampler {
+	// the grid has m columns and n rows, as close to a square as possible
+	m := int(math.Sqrt(float64(samples)))
+	for samples%m != 0 {
+		m--
+	}
+	return &stratifiedSampler{samples: samples, m: m, n: samples / m, seed: seed}
+}
+
+func (s *stratifiedSampler) StartPixelSample(pixel, sample int) {
+	s.pixel, s.sample, s.dimension = pixel, sample, 0
+}
+
+func (s *stratifiedSampler) SetDimension(dimension int) {
+	s.dimension = dimension
+}
+
+// pattern returns a pattern for the current dimension and shuffles the sample, so
+// different pixels and dimensions don't use samples in the same order
+func (s *stratifiedSampler) pattern() (uint32, uint32) {
+	p := uint32(hashSample(s.seed, s.pixel, s.dimension))
+	sample := uint32(s.sample % s.samples)
+	return p, permute(sample, uint32(s.samples), p*0x51633e2d)
+}
+
+func (s *stratifiedSampler) Get1D() float64 {
+	p, sample := s.pattern()
+	s.dimension++
+	stratum := permute(sample, uint32(s.samples), p*0x68bc21eb)
+	return (float64(stratum) + randFloat(sample, p*0x967a889b)) / float64(s.samples)
+}
+
+func (s *stratifiedSampler) Get2D() (float64, float64) {
+	p, sample := s.pattern()
+	s.dimension += 2
+	m, n := uint32(s.m), uint32(s.n)
+	sx := permute(sample%m, m, p*0xa511e9b3)
+	sy := permute(sample/m, n, p*0x63d83595)
+	jx := randFloat(sample, p*0xa399d265)
+	jy := randFloat(sample, p*0x711ad6a5)
+	x := (float64(sample%m) + (float64(sy)+jx)/float64(n)) / float64(m)
+	y := (float64(sample/m) + (float64(sx)+jy)/float64(m)) / float64(n)
+	return x, y
+}
+
+// permute returns the element at index i of a random permutation of l elements selected by p
+func permute(i, l, p uint32) uint32 {
+	w := l - 1
+	w |= w >> 1
+	w |= w >> 2
+	w |= w >> 4
+	w |= w >> 8
+	w |= w >> 16
+	for {
+		i ^= p
+		i *= 0xe170893d
+		i ^= p >> 16
+		i ^= (i & w) >> 4
+		i ^= p >> 8
+		i *= 0x0929eb3f
+		i ^= p >> 23
+		i ^= (i & w) >> 1
+		i *= 1 | p>>27
+		i *= 0x6935fa69
+		i ^= (i & w) >> 11
+		i *= 0x74dcb303
+		i ^= (i & w) >> 2
+		i *= 0x9e501cc3
+		i ^= (i & w) >> 2
+		i *= 0xc860a3df
+		i &= w
+		i ^= i >> 5
+		if i < l {
+			break
+		}
+	}
+	return (i + p) % l
+}
+
+// randFloat returns a random value in [0, 1) selected by i and p
+func randFloat(i, p uint32) float64 {
+	i ^= p
+	i ^= i >> 17
+	i ^= i >> 10
+	i *= 0xb36534e5
+	i ^= i >> 12
+	i ^= i >> 21
+	i *= 0x93fc4795
+	i ^= 0xdf6e307f
+	i ^= i >> 17
+	i *= 1 | p>>18
+	return float64(i) / 4294967808
+}
+
+// haltonSampler uses the Halton sequence with a prime base for every dimension, digits are
+// randomly permuted in every pixel (Owen scrambling), so pixels don't share the same values
+type haltonSampler struct {
+	seed          int64
+	pixel, sample int
+	dimension     int
+	rng           RNG
+}
+
+// haltonPrimes are bases of Halton dimensions, further dimensions are independent random values
+var haltonPrimes = primes(1000)
+
+func primes(n int) []uint32 {
+	composite := make([]bool, n)
+	var primes []uint32
+	for i := 2; i < n; i++ {
+		if composite[i] {
+			continue
+		}
+		primes = append(primes, uint32(i))
+		for j := i * i; j < n; j += i {
+			composite[j] = true
+		}
+	}
+	return primes
+}
+
+func (s *haltonSampler) StartPixelSample(pixel, sample int) {
+	s.pixel, s.sample, s.dimension = pixel, sample, 0
+	pixelRNG(&s.rng, s.seed, pixel, sample)
+}
+
+func (s *haltonSampler) SetDimension(dimension int) {
+	s.dimension = dimension
+}
+
+func (s *haltonSampler) Get1D() float64 {
+	dimension := s.dimension
+	s.dimension++
+	if dimension >= len(haltonPrimes) {
+		return s.rng.Float64()
+	}
+	return scrambledRadicalInverse(haltonPrimes[dimension], uint64(s.sample), hashSample(s.seed, s.pixel, dimension))
+}
+
+func (s *haltonSampler) Get2D() (float64, float64) {
+	x := s.Get1D()
+	return x, s.Get1D()
+}
+
+// scrambledRadicalInverse mirrors digits of index in base around the decimal point and permutes
+// every digit depending on digits before it. Digits past the last digit of index are all
+// permuted zeros, which are independent random digits, so they are replaced by a random value
+func scrambledRadicalInverse(base uint32, index uint64, seed uint64) float64 {
+	invBase := 1 / float64(base)
+	scale := invBase
+	result := 0.0
+	prefix := seed
+	for index > 0 {
+		digit := uint32(index % uint64(base))
+		index /= uint64(base)
+		result += float64(permute(digit, base, uint32(prefix))) * scale
+		scale *= invBase
+		prefix = mix64(prefix ^ uint64(digit+1))
+	}
+	return math.Min(result+float64(mix64(prefix)>>11)/(1<<53)*scale*float64(base), oneMinusEpsilon)
+}
+
+// oneMinusEpsilon is the largest float64 less than 1
+const oneMinusEpsilon = 1 - 1.0/(1<<53)
+
+// sobolSampler uses the first two dimensions of the Sobol sequence scrambled by hashing
+// (Burley 2020). Every pair of dimensions shuffles samples with a different seed, so it
+// stays well stratified in 2D and dimensions are independent of each other. It works best
+// with a power of two samples per pixel
+type sobolSampler struct {
+	seed          int64
+	pixel, sample int
+	dimension     int
+}
+
+func (s *sobolSampler) StartPixelSample(pixel, sample int) {
+	s.pixel, s.sample, s.dimension = pixel, sample, 0
+}
+
+func (s *sobolSampler) SetDimension(dimension int) {
+	s.dimension = dimension
+}
+
+func (s *sobolSampler) Get1D() float64 {
+	seed := hashSample(s.seed, s.pixel, s.dimension)
+	s.dimension++
+	index := nestedUniformScramble(uint32(s.sample), uint32(seed))
+	return sobolFloat(nestedUniformScramble(bits.Reverse32(index), uint32(seed>>32)))
+}
+
+func (s *sobolSampler) Get2D() (float64, float64) {
+	seed := hashSample(s.seed, s.pixel, s.dimension)
+	s.dimension += 2
+	index := nestedUniformScramble(uint32(s.sample), uint32(seed))
+	x, y := sobol2(index)
+	seed = mix64(seed)
+	return sobolFloat(nestedUniformScramble(x, uint32(seed))), sobolFloat(nestedUniformScramble(y, uint32(seed>>32)))
+}
+
+// sobol2 returns the first two dimensions of the Sobol sequence
+func sobol2(index uint32) (uint32, uint32) {
+	x := bits.Reverse32(index)
+	var y uint32
+	for v := uint32(1 << 31); index != 0; index >>= 1 {
+		if index&1 != 0 {
+			y ^= v
+		}
+		v ^= v >> 1
+	}
+	return x, y
+}
+
+// nestedUniformScramble applies Owen scrambling to bits of x, flipping every bit
+// depending on the bits above it
+func nestedUniformScramble(x, seed uint32) uint32 {
+	x = bits.Reverse32(x)
+	x = laineKarras(x, seed)
+	return bits.Reverse32(x)
+}
+
+// laineKarras is a hash in which every bit depends only on the bits below it
+func laineKarras(x, seed uint32) uint32 {
+	x += seed
+	x ^= x * 0x6c50b47c
+	x ^= x * 0xb82f1e52
+	x ^= x * 0xc7afe638
+	x ^= x * 0x8d22f6e6
+	return x
+}
+
+func sobolFloat(x uint32) float64 {
+	return float64(x) / (1 << 32)
+}
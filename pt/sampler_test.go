@@ -0,0 +1,109 @@
+package pt
+
+import (
+	"math"
+	"testing"
+)
+
+var samplerNames = []string{"independent", "stratified", "halton", "sobol"}
+
+// samplerError returns root mean square error of estimates of the area of a quarter
+// of the unit disk made in many pixels, using the 2D dimensions starting at dimension
+func samplerError(t *testing.T, name string, samples, dimension int) float64 {
+	sampler, err := NewSampler(name, samples, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const pixels = 256
+	sum := 0.0
+	for pixel := 0; pixel < pixels; pixel++ {
+		inside := 0
+		for sample := 0; sample < samples; sample++ {
+			sampler.StartPixelSample(pixel, sample)
+			sampler.SetDimension(dimension)
+			x, y := sampler.Get2D()
+			if x < 0 || x >= 1 || y < 0 || y >= 1 {
+				t.Fatalf("%s: sample %v, %v outside [0, 1)", name, x, y)
+			}
+			if x*x+y*y < 1 {
+				inside++
+			}
+		}
+		e := float64(inside)/float64(samples) - math.Pi/4
+		sum += e * e
+	}
+	return math.Sqrt(sum / pixels)
+}
+
+func TestSamplerConvergence(t *testing.T) {
+	for _, dimension := range []int{pixelDimension, bounceStart(3) + bsdfDimension} {
+		independent := samplerError(t, "independent", 64, dimension)
+		for _, name := range samplerNames[1:] {
+			// Halton dimensions with large bases are poorly stratified at low sample counts
+			if name == "halton" && dimension > lensDimension {
+				continue
+			}
+			if e := samplerError(t, name, 64, dimension); e > independent/2 {
+				t.Errorf("%s: dimension %d has error %.4f, independent sampler %.4f", name, dimension, e, independent)
+			}
+		}
+	}
+}
+
+// TestSamplerDimensions checks that values of different dimensions aren't correlated
+func TestSamplerDimensions(t *testing.T) {
+	for _, name := range samplerNames {
+		sampler, err := NewSampler(name, 64, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		const n = 64 * 64
+		var sx, sy, sxy float64
+		for pixel := 0; pixel < n/64; pixel++ {
+			for sample := 0; sample < 64; sample++ {
+				sampler.StartPixelSample(pixel, sample)
+				x := sampler.Get1D()
+				sampler.SetDimension(bounceStart(1))
+				y := sampler.Get1D()
+				sx, sy, sxy = sx+x, sy+y, sxy+x*y
+			}
+		}
+		covariance := sxy/n - sx/n*sy/n
+		if math.Abs(covariance) > 0.005 {
+			t.Errorf("%s: covariance of dimensions is %.4f", name, covariance)
+		}
+	}
+}
+
+// TestDimensionLayout checks that uses of dimensions of a bounce don't overlap, so the seed of
+// random walks doesn't repeat the choice of the lobe which started them
+func TestDimensionLayout(t *testing.T) {
+	if lightDimension+3 > bsdfDimension || bsdfDimension+9 > walkDimension || walkDimension >= hitDimension || hitDimension >= bounceDimensions {
+		t.Errorf("dimensions of a bounce overlap: light %d, BSDF %d, walk %d, hit %d of %d",
+			lightDimension, bsdfDimension, walkDimension, hitDimension, bounceDimensions)
+	}
+}
+
+func TestNewSampler(t *testing.T) {
+	if _, err := NewSampler("unknown", 16, 0); err == nil {
+		t.Error("expected an error for an unknown sampler")
+	}
+	// prime numbers of samples are stratified in a grid with a single column
+	for _, samples := range []int{1, 7, 12} {
+		sampler, err := NewSampler("stratified", samples, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen := make([]bool, samples)
+		for sample := 0; sample < samples; sample++ {
+			sampler.StartPixelSample(0, sample)
+			x := sampler.Get1D()
+			seen[int(x*float64(samples))] = true
+		}
+		for i, ok := range seen {
+			if !ok {
+				t.Errorf("%d samples: stratum %d is empty", samples, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package pt
+
+import (
+	"fmt"
+	"log"
+)
+
+// Scene holds everything needed to render an image
+type Scene struct {
+	world         HittableList
+	built         bool
+	camera        Camera
+	hasCamera     bool
+	envMap        Texture
+	atm           []Atmosphere
+	settings      Settings
+	spheres       []Sphere
+	triangles     [][]Triangle
+	named         []MaterialHash
+	imageArray    []ImageHash
+	materialArray []MaterialHash
+	bvhOptions    BVHOptions
+	bvhStats      BVHStats
+	// meshes are bottom-level BVHs of triangles, kept when the scene is rebuilt
+	meshes    []*BVH
+	instances []Instance
+	// lights are emissive spheres and triangles sampled directly
+	lights lightList
+}
+
+// NewScene returns an empty scene with black environment
+func NewScene() *Scene {
+	return &Scene{
+		envMap:     NewConstant(Color{0, 0, 0}),
+		settings:   DefaultSettings(),
+		bvhOptions: DefaultBVHOptions(),
+	}
+}
+
+// SetCamera sets the camera used for rendering the scene
+func (scene *Scene) SetCamera(camera Camera) {
+	scene.camera = camera
+	scene.hasCamera = true
+}
+
+// AddSphere adds a sphere to the scene
+func (scene *Scene) AddSphere(center Tuple, radius float64, material Material) {
+	scene.spheres = append(scene.spheres, Sphere{center, radius, material})
+	scene.built = false
+}
+
+// AddOBJ loads triangles from an OBJ file, transformed by the given matrix.
+// If material is nil, materials from MTL files referenced by the OBJ file are used
+func (scene *Scene) AddOBJ(path string, transform Transform, material *Material, smooth bool) error {
+	override := material != nil
+	m := Material{}
+	if override {
+		m = triangleMaterial(*material)
+	}
+	scene.built = false
+	return loadOBJ(path, &scene.triangles, transform, &scene.imageArray, &scene.materialArray, m, smooth, override)
+}
+
+// LoadMesh loads an OBJ file as a mesh which is placed in the scene with AddInstance.
+// If material is nil, materials from MTL files referenced by the OBJ file are used
+func (scene *Scene) LoadMesh(path string, material *Material, smooth bool) (*Mesh, error) {
+	override := material != nil
+	m := Material{}
+	if override {
+		m = triangleMaterial(*material)
+	}
+	mesh := &Mesh{}
+	if err := loadOBJ(path, &mesh.triangles, Identity(), &scene.imageArray, &scene.materialArray, m, smooth, override); err != nil {
+		return nil, err
+	}
+	return mesh, nil
+}
+
+// AddInstance places the mesh in the scene transformed by the given matrix, triangles of the mesh
+// are shared by all its instances. If material isn't nil, it replaces materials of the mesh
+func (scene *Scene) AddInstance(mesh *Mesh, transform Transform, material *Material) {
+	instance := Instance{mesh: mesh, transform: transform}
+	if material != nil {
+		m := triangleMaterial(*material)
+		instance.material = &m
+	}
+	scene.instances = append(scene.instances, instance)
+	scene.built = false
+}
+
+// triangleMaterial returns the material with image textures using texture coordinates of triangles
+func triangleMaterial(m Material) Material {
+	if m.albedo.mode == SphereImageUV {
+		m.albedo.mode = TriangleImageUV
+	}
+	if m.principled != nil {
+		p := *m.principled
+		for _, t := range p.textures() {
+			if t.mode == SphereImageUV {
+				t.mode = TriangleImageUV
+			}
+		}
+		m.principled = &p
+	}
+	if m.maps != nil {
+		maps := *m.maps
+		for _, t := range maps.textures() {
+			if *t != nil && (*t).mode == SphereImageUV {
+				converted := **t
+				converted.mode = TriangleImageUV
+				*t = &converted
+			}
+		}
+		m.maps = &maps
+	}
+	if m.opacity != nil && m.opacity.opacity.mode == SphereImageUV {
+		o := *m.opacity
+		o.opacity.mode = TriangleImageUV
+		m.opacity = &o
+	}
+	if m.mix != nil {
+		x := *m.mix
+		x.a, x.b = triangleMaterial(x.a), triangleMaterial(x.b)
+		if x.weight.mode == SphereImageUV {
+			x.weight.mode = TriangleImageUV
+		}
+		m.mix = &x
+	}
+	if m.layer != nil {
+		c := *m.layer
+		c.base = triangleMaterial(c.base)
+		m.layer = &c
+	}
+	return m
+}
+
+// AddMaterial registers a named material, so it's listed by Info
+func (scene *Scene) AddMaterial(name string, material Material) {
+	scene.named = append(scene.named, MaterialHash{material, hash(name), name})
+}
+
+// SetEnvironment sets the texture seen by rays which don't hit anything
+func (scene *Scene) SetEnvironment(envMap Texture) {
+	scene.envMap = envMap
+}
+
+// SetSky enables Nishita sky model with a sun in the given direction, it replaces the environment texture
+func (scene *Scene) SetSky(sunDirection Tuple) {
+	scene.atm = []Atmosphere{NewEarthAtmosphere(sunDirection)}
+}
+
+// SetBVHOptions sets options used for building BVHs of the scene
+func (scene *Scene) SetBVHOptions(opts BVHOptions) {
+	scene.bvhOptions = opts
+	scene.meshes = nil
+	for _, instance := range scene.instances {
+		instance.mesh.bvh = nil
+	}
+	scene.built = false
+}
+
+// BuildBVH builds BVHs of the scene if they aren't built yet and returns their stats,
+// Render calls it automatically
+func (scene *Scene) BuildBVH() BVHStats {
+	scene.buildWorld()
+	return scene.bvhStats
+}
+
+// Settings returns render settings read from the scene file, or default settings
+func (scene *Scene) Settings() Settings {
+	return scene.settings
+}
+
+// NamedMaterial is a material with a name from a scene or MTL file
+type NamedMaterial struct {
+	Name     string
+	Material Material
+}
+
+// Info summarizes contents of a scene
+type Info struct {
+	Objects, Triangles, Spheres int
+	// Instances is the number of mesh instances, InstancedTriangles is the number of triangles they show
+	Instances, InstancedTriangles int
+	// Min and Max are corners of the bounding box of the scene, Empty is set if there's nothing in the scene
+	Min, Max    Tuple
+	Empty       bool
+	Environment string
+	Materials   []NamedMaterial
+}
+
+// Info returns a summary of the scene without building BVHs
+func (scene *Scene) Info() Info {
+	info := Info{
+		Objects: len(scene.triangles),
+		Spheres: len(scene.spheres),
+		Empty:   true,
+	}
+
+	var bounds AABB
+	add := func(box AABB) {
+		if info.Empty {
+			bounds = box
+			info.Empty = false
+			return
+		}
+		bounds = bounds.union(box)
+	}
+	for _, object := range scene.triangles {
+		info.Triangles += len(object)
+		for i := range object {
+			add(object[i].boundingBox())
+		}
+	}
+	for i := range scene.spheres {
+		add(scene.spheres[i].boundingBox())
+	}
+	for _, instance := range scene.instances {
+		info.Instances++
+		info.InstancedTriangles += instance.mesh.Triangles()
+		if instance.mesh.Triangles() > 0 {
+			add(instance.transform.box(instance.mesh.bounds()))
+		}
+	}
+	info.Min, info.Max = bounds.min, bounds.max
+
+	if len(scene.atm) > 0 {
+		sun := scene.atm[0].sunDirection
+		info.Environment = fmt.Sprintf("sky with sun in direction (%.3g, %.3g, %.3g)", sun.x, sun.y, sun.z)
+	} else {
+		info.Environment = scene.envMap.String()
+	}
+
+	// materials from MTL files are kept separately so they don't clash with scene names
+	for _, m := range append(scene.named, scene.materialArray...) {
+		info.Materials = append(info.Materials, NamedMaterial{m.name, m.material})
+	}
+	return info
+}
+
+// buildWorld builds a BVH for every object of the scene which doesn't have one yet,
+// and a top-level BVH over them and spheres
+func (scene *Scene) buildWorld() {
+	if scene.built {
+		return
+	}
+	triangles := scene.triangles
+	numTris, done := 0, 0
+
+	log.Println("Building BVHs...")
+	for i := len(scene.meshes); i < len(triangles); i++ {
+		numTris += len(triangles[i])
+	}
+	for i := len(scene.meshes); i < len(triangles); i++ {
+		objects := make([]Hittable, len(triangles[i]))
+		for j := range triangles[i] {
+			objects[j] = &triangles[i][j]
+		}
+		scene.meshes = append(scene.meshes, getBVH(objects, scene.bvhOptions))
+		done += len(triangles[i])
+		fmt.Fprintf(log.Writer(), "\r%.2f%% (%d/%d triangles, %d/%d objects)", float64(done)/float64(numTris)*100, done, numTris, i+1, len(triangles))
+	}
+	if numTris > 0 {
+		fmt.Fprintln(log.Writer())
+	}
+
+	stats := BVHStats{}
+	objects := []Hittable{}
+	for _, mesh := range scene.meshes {
+		if len(mesh.nodes) > 0 {
+			stats = stats.add(mesh.stats(scene.bvhOptions))
+			objects = append(objects, mesh)
+		}
+	}
+	for i := range scene.spheres {
+		objects = append(objects, &scene.spheres[i])
+	}
+	// instances of the same mesh share its BVH
+	for i := range scene.instances {
+		mesh := scene.instances[i].mesh
+		if mesh.bvh == nil {
+			mesh.build(scene.bvhOptions)
+			stats = stats.add(mesh.bvh.stats(scene.bvhOptions))
+		}
+		if len(mesh.bvh.nodes) > 0 {
+			objects = append(objects, &scene.instances[i])
+		}
+	}
+	world := getBVH(objects, scene.bvhOptions)
+	stats = stats.add(world.stats(scene.bvhOptions))
+	log.Printf("Built BVHs: %d nodes, %d leaves, %.2f objects per leaf, max depth %d, SAH cost %.2f\n", stats.Nodes, stats.Leaves, stats.AverageLeafSize, stats.MaxDepth, stats.SAHCost)
+
+	scene.world = HittableList{world, scene.atm}
+	scene.lights = newLightList(scene.collectLights())
+	if len(scene.lights.lights) > 0 {
+		log.Printf("Sampling %d lights directly\n", len(scene.lights.lights))
+	}
+	scene.bvhStats = stats
+	scene.built = true
+}
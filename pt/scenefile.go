@@ -0,0 +1,1220 @@
+package pt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sceneFile is the layout of a JSON scene file, see README.md for reference
+type sceneFile struct {
+	Render      renderJSON                 `json:"render"`
+	Camera      *cameraJSON                `json:"camera"`
+	Materials   map[string]json.RawMessage `json:"materials"`
+	Spheres     []sphereJSON               `json:"spheres"`
+	Meshes      []meshJSON                 `json:"meshes"`
+	Models      map[string]modelJSON       `json:"models"`
+	Instances   []instanceJSON             `json:"instances"`
+	Environment json.RawMessage            `json:"environment"`
+	Sky         *skyJSON                   `json:"sky"`
+}
+
+type renderJSON struct {
+	Width       *int    `json:"width"`
+	Height      *int    `json:"height"`
+	Samples     *int    `json:"samples"`
+	Depth       *int    `json:"depth"`
+	Preview     *bool   `json:"preview"`
+	Jitter      *bool   `json:"jitter"`
+	Sampler     *string `json:"sampler"`
+	Seed        *int64  `json:"seed"`
+	ToneMapping *bool   `json:"toneMapping"`
+	Output      *string `json:"output"`
+	Format      *string `json:"format"`
+}
+
+type cameraJSON struct {
+	From          []float64 `json:"from"`
+	To            []float64 `json:"to"`
+	Up            []float64 `json:"up"`
+	FocalLength   *float64  `json:"focalLength"`
+	FNumber       *float64  `json:"fNumber"`
+	FocusDistance *float64  `json:"focusDistance"`
+}
+
+type sphereJSON struct {
+	Center   []float64       `json:"center"`
+	Radius   float64         `json:"radius"`
+	Material json.RawMessage `json:"material"`
+}
+
+type meshJSON struct {
+	Path      string          `json:"path"`
+	Material  json.RawMessage `json:"material"`
+	Smooth    *bool           `json:"smooth"`
+	Transform []transformJSON `json:"transform"`
+}
+
+type modelJSON struct {
+	Path     string          `json:"path"`
+	Material json.RawMessage `json:"material"`
+	Smooth   *bool           `json:"smooth"`
+}
+
+type instanceJSON struct {
+	Model     string          `json:"model"`
+	Material  json.RawMessage `json:"material"`
+	Transform []transformJSON `json:"transform"`
+}
+
+type transformJSON struct {
+	Translate []float64   `json:"translate"`
+	Scale     []float64   `json:"scale"`
+	RotateX   *float64    `json:"rotateX"`
+	RotateY   *float64    `json:"rotateY"`
+	RotateZ   *float64    `json:"rotateZ"`
+	Axis      []float64   `json:"axis"`
+	Angle     *float64    `json:"angle"`
+	Shear     []float64   `json:"shear"`
+	LookAt    *lookAtJSON `json:"lookAt"`
+}
+
+type lookAtJSON struct {
+	From []float64 `json:"from"`
+	To   []float64 `json:"to"`
+	Up   []float64 `json:"up"`
+}
+
+type skyJSON struct {
+	SunDirection []float64 `json:"sunDirection"`
+}
+
+type materialJSON struct {
+	Type               string          `json:"type"`
+	Albedo             json.RawMessage `json:"albedo"`
+	Roughness          json.RawMessage `json:"roughness"`
+	IOR                json.RawMessage `json:"ior"`
+	Clearcoat          json.RawMessage `json:"clearcoat"`
+	ClearcoatRoughness json.RawMessage `json:"clearcoatRoughness"`
+	Metalicity         json.RawMessage `json:"metalicity"`
+	Transmission       json.RawMessage `json:"transmission"`
+	Conductor          json.RawMessage `json:"conductor"`
+	// sigma makes diffuse reflection rough (Oren-Nayar), in degrees
+	Sigma json.RawMessage `json:"sigma"`
+	// thin film on BSDF materials, its thickness is in nanometers
+	FilmThickness json.RawMessage `json:"filmThickness"`
+	FilmIOR       json.RawMessage `json:"filmIOR"`
+
+	// absorption inside dielectrics, either a coefficient or the color left after a distance
+	Absorption            json.RawMessage `json:"absorption"`
+	Transmittance         json.RawMessage `json:"transmittance"`
+	TransmittanceDistance *float64        `json:"transmittanceDistance"`
+	Priority              *int            `json:"priority"`
+
+	// opacity cuts out surfaces of materials of any type, it's a number, a texture or "alpha"
+	// for the alpha channel of the albedo image, which is sampled stochastically without threshold
+	Opacity          json.RawMessage `json:"opacity"`
+	OpacityThreshold *float64        `json:"opacityThreshold"`
+
+	// materials of mix materials, given by names or inline, chosen by weight or by Fresnel
+	// reflectance of a dielectric with index of refraction fresnel
+	Materials []json.RawMessage `json:"materials"`
+	Weight    json.RawMessage   `json:"weight"`
+	Fresnel   *float64          `json:"fresnel"`
+
+	// base material under the coat of layered materials and thickness of the coat
+	Base      json.RawMessage `json:"base"`
+	Thickness *float64        `json:"thickness"`
+
+	// mean free path inside subsurface materials, a number or a color
+	Radius json.RawMessage `json:"radius"`
+	Scale  *float64        `json:"scale"`
+
+	// parameters of principled materials
+	BaseColor           json.RawMessage `json:"baseColor"`
+	Metallic            json.RawMessage `json:"metallic"`
+	Subsurface          json.RawMessage `json:"subsurface"`
+	Specular            json.RawMessage `json:"specular"`
+	SpecularTint        json.RawMessage `json:"specularTint"`
+	Anisotropic         json.RawMessage `json:"anisotropic"`
+	AnisotropicRotation json.RawMessage `json:"anisotropicRotation"`
+	Sheen               json.RawMessage `json:"sheen"`
+	SheenTint           json.RawMessage `json:"sheenTint"`
+	ClearcoatGloss      json.RawMessage `json:"clearcoatGloss"`
+	SpecTrans           json.RawMessage `json:"specTrans"`
+	Thin                json.RawMessage `json:"thin"`
+	DiffTrans           json.RawMessage `json:"diffTrans"`
+	Flatness            json.RawMessage `json:"flatness"`
+	ScatterDistance     json.RawMessage `json:"scatterDistance"`
+}
+
+// principledField is a parameter of a principled material named as in JSON
+type principledField struct {
+	name    string
+	raw     json.RawMessage
+	texture *Texture
+}
+
+// principledFields returns fields of principled materials with the parameters they set
+func (m *materialJSON) principledFields(p *PrincipledParams) []principledField {
+	return []principledField{
+		{"baseColor", m.BaseColor, &p.BaseColor},
+		{"metallic", m.Metallic, &p.Metallic},
+		{"subsurface", m.Subsurface, &p.Subsurface},
+		{"specular", m.Specular, &p.Specular},
+		{"specularTint", m.SpecularTint, &p.SpecularTint},
+		{"roughness", m.Roughness, &p.Roughness},
+		{"anisotropic", m.Anisotropic, &p.Anisotropic},
+		{"anisotropicRotation", m.AnisotropicRotation, &p.AnisotropicRotation},
+		{"sheen", m.Sheen, &p.Sheen},
+		{"sheenTint", m.SheenTint, &p.SheenTint},
+		{"clearcoat", m.Clearcoat, &p.Clearcoat},
+		{"clearcoatGloss", m.ClearcoatGloss, &p.ClearcoatGloss},
+		{"specTrans", m.SpecTrans, &p.SpecTrans},
+		{"ior", m.IOR, &p.IOR},
+		{"thin", m.Thin, &p.Thin},
+		{"diffTrans", m.DiffTrans, &p.DiffTrans},
+		{"flatness", m.Flatness, &p.Flatness},
+		{"scatterDistance", m.ScatterDistance, &p.ScatterDistance},
+	}
+}
+
+type textureJSON struct {
+	Type   string            `json:"type"`
+	Color  json.RawMessage   `json:"color"`
+	Colors []json.RawMessage `json:"colors"`
+	Scale  []float64         `json:"scale"`
+	Width  float64           `json:"width"`
+	Path   string            `json:"path"`
+	Normal string            `json:"normal"`
+}
+
+// conductorJSON is a complex index of refraction, or reflectivity and edge tint
+type conductorJSON struct {
+	Eta          json.RawMessage `json:"eta"`
+	K            json.RawMessage `json:"k"`
+	Reflectivity json.RawMessage `json:"reflectivity"`
+	EdgeTint     json.RawMessage `json:"edgeTint"`
+}
+
+// sceneLoader keeps state shared between entries of a single scene file
+type sceneLoader struct {
+	dir       string
+	scene     *Scene
+	materials map[string]Material
+	models    map[string]*Mesh
+}
+
+// LoadScene reads a JSON scene file, adjust (if not nil) can change render
+// settings before they are validated and used to set up the camera
+func LoadScene(path string, adjust func(*Settings)) (*Scene, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file sceneFile
+	if err := decodeStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, jsonError(data, err))
+	}
+
+	scene, err := file.build(filepath.Dir(path), adjust)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return scene, nil
+}
+
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// jsonError adds line and column of the error to errors returned by encoding/json
+func jsonError(data []byte, err error) error {
+	var offset int64
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	default:
+		return err
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	line := 1 + bytes.Count(data[:offset], []byte("\n"))
+	column := int(offset) - bytes.LastIndexByte(data[:offset], '\n')
+	return fmt.Errorf("line %d, column %d: %w", line, column, err)
+}
+
+func (file *sceneFile) build(dir string, adjust func(*Settings)) (*Scene, error) {
+	scene := NewScene()
+	loader := &sceneLoader{dir: dir, scene: scene, materials: map[string]Material{}, models: map[string]*Mesh{}}
+
+	settings := file.Render.settings()
+	if adjust != nil {
+		adjust(&settings)
+	}
+	if err := settings.validate(); err != nil {
+		return nil, fmt.Errorf("render: %w", err)
+	}
+	scene.settings = settings
+
+	if file.Camera == nil {
+		return nil, fmt.Errorf("camera: missing camera description")
+	}
+	camera, err := file.Camera.camera(float64(settings.Width) / float64(settings.Height))
+	if err != nil {
+		return nil, fmt.Errorf("camera: %w", err)
+	}
+	scene.SetCamera(camera)
+
+	// named materials have to be loaded first so spheres and meshes can refer to them
+	names := make([]string, 0, len(file.Materials))
+	for name := range file.Materials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		raw := file.Materials[name]
+		var m materialJSON
+		if err := decodeStrict(raw, &m); err != nil {
+			return nil, fmt.Errorf("materials.%s: %w", name, err)
+		}
+		material, err := loader.material(m, name)
+		if err != nil {
+			return nil, fmt.Errorf("materials.%s: %w", name, err)
+		}
+		loader.materials[name] = material
+		scene.AddMaterial(name, material)
+	}
+
+	for i, s := range file.Spheres {
+		if err := loader.sphere(s, fmt.Sprintf("spheres[%d]", i)); err != nil {
+			return nil, fmt.Errorf("spheres[%d]: %w", i, err)
+		}
+	}
+
+	for i, m := range file.Meshes {
+		if err := loader.mesh(m, fmt.Sprintf("meshes[%d]", i)); err != nil {
+			return nil, fmt.Errorf("meshes[%d]: %w", i, err)
+		}
+	}
+
+	names = names[:0]
+	for name := range file.Models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := loader.model(file.Models[name], name); err != nil {
+			return nil, fmt.Errorf("models.%s: %w", name, err)
+		}
+	}
+
+	for i, inst := range file.Instances {
+		if err := loader.instance(inst, fmt.Sprintf("instances[%d]", i)); err != nil {
+			return nil, fmt.Errorf("instances[%d]: %w", i, err)
+		}
+	}
+
+	if len(file.Environment) > 0 {
+		envMap, err := loader.texture(file.Environment)
+		if err != nil {
+			return nil, fmt.Errorf("environment: %w", err)
+		}
+		scene.SetEnvironment(envMap)
+	}
+
+	if file.Sky != nil {
+		sunDirection, err := vec3(file.Sky.SunDirection, "sunDirection")
+		if err != nil {
+			return nil, fmt.Errorf("sky: %w", err)
+		}
+		scene.SetSky(sunDirection)
+	}
+
+	return scene, nil
+}
+
+func (r renderJSON) settings() Settings {
+	settings := DefaultSettings()
+	if r.Width != nil {
+		settings.Width = *r.Width
+	}
+	if r.Height != nil {
+		settings.Height = *r.Height
+	}
+	if r.Samples != nil {
+		settings.Samples = *r.Samples
+	}
+	if r.Depth != nil {
+		settings.Depth = *r.Depth
+	}
+	if r.Preview != nil {
+		settings.Preview = *r.Preview
+	}
+	if r.Jitter != nil {
+		settings.Jitter = *r.Jitter
+	}
+	if r.Sampler != nil {
+		settings.Sampler = *r.Sampler
+	}
+	if r.Seed != nil {
+		settings.Seed = *r.Seed
+	}
+	if r.ToneMapping != nil {
+		settings.ToneMapping = *r.ToneMapping
+	}
+	if r.Output != nil {
+		settings.Output = *r.Output
+	}
+	if r.Format != nil {
+		settings.Format = *r.Format
+	}
+	return settings
+}
+
+func (c cameraJSON) camera(aspect float64) (Camera, error) {
+	from, err := vec3(c.From, "from")
+	if err != nil {
+		return Camera{}, err
+	}
+	to, err := vec3(c.To, "to")
+	if err != nil {
+		return Camera{}, err
+	}
+	up := Tuple{0, 1, 0, 0}
+	if c.Up != nil {
+		if up, err = vec3(c.Up, "up"); err != nil {
+			return Camera{}, err
+		}
+	}
+	if from.Equals(to) {
+		return Camera{}, fmt.Errorf("\"from\" and \"to\" can't be the same point")
+	}
+
+	fLength, fNumber := 40.0, 1024.0
+	focusDistance := to.Subtract(from).Magnitude()
+	if c.FocalLength != nil {
+		fLength = *c.FocalLength
+	}
+	if c.FNumber != nil {
+		fNumber = *c.FNumber
+	}
+	if c.FocusDistance != nil {
+		focusDistance = *c.FocusDistance
+	}
+	if fLength <= 0 || fNumber <= 0 || focusDistance <= 0 {
+		return Camera{}, fmt.Errorf("focalLength, fNumber and focusDistance have to be positive")
+	}
+
+	return NewCamera(from, to, up, fLength, aspect, fNumber, focusDistance), nil
+}
+
+func (l *sceneLoader) sphere(s sphereJSON, where string) error {
+	center, err := vec3(s.Center, "center")
+	if err != nil {
+		return err
+	}
+	if s.Radius <= 0 {
+		return fmt.Errorf("radius has to be positive, got %g", s.Radius)
+	}
+	material, err := l.materialRef(s.Material, where)
+	if err != nil {
+		return fmt.Errorf("material: %w", err)
+	}
+	l.scene.AddSphere(center, s.Radius, material)
+	return nil
+}
+
+func (l *sceneLoader) mesh(m meshJSON, where string) error {
+	if m.Path == "" {
+		return fmt.Errorf("missing \"path\"")
+	}
+	path := l.path(m.Path)
+	if !fileExists(path) {
+		return fmt.Errorf("path: file %q does not exist", path)
+	}
+
+	material, err := l.optionalMaterial(m.Material, where)
+	if err != nil {
+		return err
+	}
+
+	smooth := true
+	if m.Smooth != nil {
+		smooth = *m.Smooth
+	}
+
+	transform, err := transformSteps(m.Transform)
+	if err != nil {
+		return err
+	}
+
+	return l.scene.AddOBJ(path, transform, material, smooth)
+}
+
+func (l *sceneLoader) model(m modelJSON, name string) error {
+	if m.Path == "" {
+		return fmt.Errorf("missing \"path\"")
+	}
+	path := l.path(m.Path)
+	if !fileExists(path) {
+		return fmt.Errorf("path: file %q does not exist", path)
+	}
+
+	material, err := l.optionalMaterial(m.Material, "models."+name)
+	if err != nil {
+		return err
+	}
+
+	smooth := true
+	if m.Smooth != nil {
+		smooth = *m.Smooth
+	}
+
+	mesh, err := l.scene.LoadMesh(path, material, smooth)
+	if err != nil {
+		return err
+	}
+	l.models[name] = mesh
+	return nil
+}
+
+func (l *sceneLoader) instance(inst instanceJSON, where string) error {
+	if inst.Model == "" {
+		return fmt.Errorf("missing \"model\"")
+	}
+	mesh, ok := l.models[inst.Model]
+	if !ok {
+		return fmt.Errorf("unknown model %q", inst.Model)
+	}
+
+	material, err := l.optionalMaterial(inst.Material, where)
+	if err != nil {
+		return err
+	}
+
+	transform, err := transformSteps(inst.Transform)
+	if err != nil {
+		return err
+	}
+
+	l.scene.AddInstance(mesh, transform, material)
+	return nil
+}
+
+// optionalMaterial resolves a material overriding materials from MTL files, nil if it's not given
+func (l *sceneLoader) optionalMaterial(raw json.RawMessage, where string) (*Material, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	material, err := l.materialRef(raw, where)
+	if err != nil {
+		return nil, fmt.Errorf("material: %w", err)
+	}
+	return &material, nil
+}
+
+// transformSteps composes transformation steps, the first step is applied first
+func transformSteps(steps []transformJSON) (Transform, error) {
+	transform := Identity()
+	for i, t := range steps {
+		step, err := t.transform()
+		if err != nil {
+			return Transform{}, fmt.Errorf("transform[%d]: %w", i, err)
+		}
+		transform = transform.Then(step)
+	}
+	return transform, nil
+}
+
+// transform returns a single transformation step, angles are in degrees
+func (t transformJSON) transform() (Transform, error) {
+	steps := 0
+	var transform Transform
+	if t.Translate != nil {
+		v, err := vec3(t.Translate, "translate")
+		if err != nil {
+			return Transform{}, err
+		}
+		transform = Translate(v.x, v.y, v.z)
+		steps++
+	}
+	if t.Scale != nil {
+		v, err := vec3(t.Scale, "scale")
+		if err != nil {
+			return Transform{}, err
+		}
+		if v.x == 0 || v.y == 0 || v.z == 0 {
+			return Transform{}, fmt.Errorf("scale values can't be zero")
+		}
+		transform = Scale(v.x, v.y, v.z)
+		steps++
+	}
+	if t.RotateX != nil {
+		transform = RotateX(*t.RotateX * math.Pi / 180)
+		steps++
+	}
+	if t.RotateY != nil {
+		transform = RotateY(*t.RotateY * math.Pi / 180)
+		steps++
+	}
+	if t.RotateZ != nil {
+		transform = RotateZ(*t.RotateZ * math.Pi / 180)
+		steps++
+	}
+	if t.Axis != nil || t.Angle != nil {
+		if t.Axis == nil || t.Angle == nil {
+			return Transform{}, fmt.Errorf("rotation around an axis needs both \"axis\" and \"angle\"")
+		}
+		axis, err := vec3(t.Axis, "axis")
+		if err != nil {
+			return Transform{}, err
+		}
+		if axis.Magnitude() == 0 {
+			return Transform{}, fmt.Errorf("axis can't be zero")
+		}
+		transform = AxisAngle(axis, *t.Angle*math.Pi/180)
+		steps++
+	}
+	if t.LookAt != nil {
+		from, err := vec3(t.LookAt.From, "from")
+		if err != nil {
+			return Transform{}, fmt.Errorf("lookAt: %w", err)
+		}
+		to, err := vec3(t.LookAt.To, "to")
+		if err != nil {
+			return Transform{}, fmt.Errorf("lookAt: %w", err)
+		}
+		up := Tuple{0, 1, 0, 0}
+		if t.LookAt.Up != nil {
+			if up, err = vec3(t.LookAt.Up, "up"); err != nil {
+				return Transform{}, fmt.Errorf("lookAt: %w", err)
+			}
+		}
+		if transform, err = LookAt(from, to, up); err != nil {
+			return Transform{}, fmt.Errorf("lookAt: %w", err)
+		}
+		steps++
+	}
+	if t.Shear != nil {
+		if len(t.Shear) != 6 {
+			return Transform{}, fmt.Errorf("shear: expected 6 numbers, got %d", len(t.Shear))
+		}
+		var err error
+		if transform, err = Shear(t.Shear[0], t.Shear[1], t.Shear[2], t.Shear[3], t.Shear[4], t.Shear[5]); err != nil {
+			return Transform{}, err
+		}
+		steps++
+	}
+	if steps != 1 {
+		return Transform{}, fmt.Errorf("expected exactly one of translate, scale, rotateX, rotateY, rotateZ, axis and angle, shear or lookAt, got %d", steps)
+	}
+	return transform, nil
+}
+
+// materialRef resolves a material given either by name or inline, inline
+// materials are listed among scene materials under the name of their user
+func (l *sceneLoader) materialRef(raw json.RawMessage, where string) (Material, error) {
+	if len(raw) == 0 {
+		return Material{}, fmt.Errorf("missing material")
+	}
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		material, ok := l.materials[name]
+		if !ok {
+			return Material{}, fmt.Errorf("unknown material %q", name)
+		}
+		return material, nil
+	}
+	var m materialJSON
+	if err := decodeStrict(raw, &m); err != nil {
+		return Material{}, err
+	}
+	material, err := l.material(m, where)
+	if err != nil {
+		return Material{}, err
+	}
+	l.scene.AddMaterial(where, material)
+	return material, nil
+}
+
+// material parses a material of any type and cuts out its surfaces with opacity, inline materials
+// combined by it are listed under where
+func (l *sceneLoader) material(m materialJSON, where string) (Material, error) {
+	var material Material
+	var err error
+	switch m.Type {
+	case "mix":
+		material, err = l.mix(m, where)
+	case "layered":
+		material, err = l.layered(m, where)
+	default:
+		if len(m.Materials) > 0 || len(m.Weight) > 0 || m.Fresnel != nil || len(m.Base) > 0 || m.Thickness != nil {
+			return Material{}, fmt.Errorf("\"materials\", \"weight\", \"fresnel\", \"base\" and \"thickness\" are only supported by mix and layered materials")
+		}
+		if m.Type == "principled" {
+			material, err = l.principled(m)
+		} else {
+			material, err = l.standard(m)
+		}
+	}
+	if err != nil {
+		return Material{}, err
+	}
+	return l.opacity(m, material)
+}
+
+// standard parses materials of all types except principled
+func (l *sceneLoader) standard(m materialJSON) (Material, error) {
+	var p PrincipledParams
+	for _, field := range m.principledFields(&p) {
+		switch field.name {
+		case "roughness", "clearcoat", "ior", "anisotropic", "anisotropicRotation":
+			continue
+		}
+		if len(field.raw) > 0 {
+			return Material{}, fmt.Errorf("%q is only supported by principled materials", field.name)
+		}
+	}
+
+	albedo := NewConstant(Color{1, 1, 1})
+	if len(m.Albedo) > 0 {
+		var err error
+		albedo, err = l.texture(m.Albedo)
+		if err != nil {
+			return Material{}, fmt.Errorf("albedo: %w", err)
+		}
+	}
+
+	if m.Type != "subsurface" && (len(m.Radius) > 0 || m.Scale != nil) {
+		return Material{}, fmt.Errorf("\"radius\" and \"scale\" are only supported by subsurface materials")
+	}
+
+	var material Material
+	switch m.Type {
+	case "lambertian":
+		material = NewLambertian(albedo)
+	case "glossy":
+		material = NewGlossy(albedo, 0, 0)
+	case "dielectric":
+		material = NewDielectric(albedo, 0, 0, 1.5)
+	case "metal":
+		material = NewMetal(albedo, 0, 0, 0)
+	case "emission":
+		material = NewEmission(albedo)
+	case "bsdf":
+		material = NewBSDF(albedo, 0, 1.5, 0, 0, 0, 0)
+	case "subsurface":
+		radius := Color{1, 1, 1}
+		if len(m.Radius) > 0 {
+			var r float64
+			if err := json.Unmarshal(m.Radius, &r); err == nil {
+				radius = Color{r, r, r}
+			} else if radius, err = parseColor(m.Radius); err != nil {
+				return Material{}, fmt.Errorf("radius: %w", err)
+			}
+		}
+		scale := 1.0
+		if m.Scale != nil {
+			scale = *m.Scale
+		}
+		if radius.r <= 0 || radius.g <= 0 || radius.b <= 0 || scale <= 0 {
+			return Material{}, fmt.Errorf("radius and scale have to be positive")
+		}
+		material = NewSubsurface(albedo, radius, scale, 0, 1.4)
+	case "":
+		return Material{}, fmt.Errorf("missing \"type\"")
+	default:
+		return Material{}, fmt.Errorf("unknown material type %q", m.Type)
+	}
+
+	// scalar parameters are either numbers or textures, which become maps of the material
+	var maps MaterialMaps
+	parameters, textures := material.parameters(), maps.textures()
+	for i, field := range []struct {
+		name string
+		raw  json.RawMessage
+	}{
+		{"roughness", m.Roughness}, {"ior", m.IOR}, {"clearcoat", m.Clearcoat}, {"clearcoatRoughness", m.ClearcoatRoughness},
+		{"metalicity", m.Metalicity}, {"transmission", m.Transmission}, {"anisotropic", m.Anisotropic}, {"anisotropicRotation", m.AnisotropicRotation},
+		{"sigma", m.Sigma}, {"filmThickness", m.FilmThickness}, {"filmIOR", m.FilmIOR},
+	} {
+		if len(field.raw) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(field.raw, parameters[i]); err == nil {
+			continue
+		}
+		texture, err := l.texture(field.raw)
+		if err != nil {
+			return Material{}, fmt.Errorf("%s: %w", field.name, err)
+		}
+		*textures[i] = &texture
+	}
+	if len(m.Roughness) > 0 && len(m.ClearcoatRoughness) == 0 && m.Type != "metal" {
+		material.clearcoatRoughness, maps.ClearcoatRoughness = material.roughness, maps.Roughness
+	}
+	if len(m.FilmThickness) > 0 || len(m.FilmIOR) > 0 {
+		if material.material != BSDF {
+			return Material{}, fmt.Errorf("%q materials don't support thin films", m.Type)
+		}
+		if len(m.FilmIOR) == 0 {
+			// soap and water
+			material.film.ior = 1.33
+		}
+	}
+	if maps != (MaterialMaps{}) {
+		material = material.WithMaps(maps)
+	}
+	if len(m.Conductor) > 0 {
+		if material.material != BSDF {
+			return Material{}, fmt.Errorf("%q materials don't support \"conductor\"", m.Type)
+		}
+		c, err := parseConductor(m.Conductor)
+		if err != nil {
+			return Material{}, fmt.Errorf("conductor: %w", err)
+		}
+		material.conductor = &c
+	}
+	if err := m.medium(&material); err != nil {
+		return Material{}, err
+	}
+	if material.ior <= 0 && material.material != Emission {
+		return Material{}, fmt.Errorf("ior has to be positive, got %g", material.ior)
+	}
+	return material, nil
+}
+
+// principled parses a principled material, every parameter is either a number or a texture
+func (l *sceneLoader) principled(m materialJSON) (Material, error) {
+	switch {
+	case len(m.Albedo) > 0:
+		return Material{}, fmt.Errorf("principled materials use \"baseColor\" instead of \"albedo\"")
+	case len(m.ClearcoatRoughness) > 0:
+		return Material{}, fmt.Errorf("principled materials use \"clearcoatGloss\" instead of \"clearcoatRoughness\"")
+	case len(m.Metalicity) > 0:
+		return Material{}, fmt.Errorf("principled materials use \"metallic\" instead of \"metalicity\"")
+	case len(m.Transmission) > 0:
+		return Material{}, fmt.Errorf("principled materials use \"specTrans\" instead of \"transmission\"")
+	case len(m.Conductor) > 0:
+		return Material{}, fmt.Errorf("principled materials don't support \"conductor\"")
+	case len(m.Absorption) > 0 || len(m.Transmittance) > 0 || m.TransmittanceDistance != nil || m.Priority != nil:
+		return Material{}, fmt.Errorf("principled materials don't support absorption and priorities")
+	case len(m.Radius) > 0 || m.Scale != nil:
+		return Material{}, fmt.Errorf("\"radius\" and \"scale\" are only supported by subsurface materials")
+	case len(m.Sigma) > 0:
+		return Material{}, fmt.Errorf("principled materials don't support \"sigma\", roughness makes their diffuse reflection retro-reflective")
+	case len(m.FilmThickness) > 0 || len(m.FilmIOR) > 0:
+		return Material{}, fmt.Errorf("principled materials don't support thin films")
+	}
+	p := DefaultPrincipled()
+	for _, field := range m.principledFields(&p) {
+		if len(field.raw) == 0 {
+			continue
+		}
+		texture, err := l.scalarTexture(field.raw)
+		if err != nil {
+			return Material{}, fmt.Errorf("%s: %w", field.name, err)
+		}
+		*field.texture = texture
+	}
+	return NewPrincipled(p), nil
+}
+
+// only checks that the material has no fields other than type, opacity and the allowed ones
+func (m *materialJSON) only(allowed ...string) error {
+	type field struct {
+		name  string
+		given bool
+	}
+	fields := []field{
+		{"albedo", len(m.Albedo) > 0}, {"clearcoatRoughness", len(m.ClearcoatRoughness) > 0},
+		{"metalicity", len(m.Metalicity) > 0}, {"transmission", len(m.Transmission) > 0},
+		{"conductor", len(m.Conductor) > 0}, {"absorption", len(m.Absorption) > 0},
+		{"transmittance", len(m.Transmittance) > 0}, {"transmittanceDistance", m.TransmittanceDistance != nil},
+		{"priority", m.Priority != nil}, {"materials", len(m.Materials) > 0}, {"weight", len(m.Weight) > 0},
+		{"fresnel", m.Fresnel != nil}, {"base", len(m.Base) > 0}, {"thickness", m.Thickness != nil},
+		{"radius", len(m.Radius) > 0}, {"scale", m.Scale != nil}, {"sigma", len(m.Sigma) > 0},
+		{"filmThickness", len(m.FilmThickness) > 0}, {"filmIOR", len(m.FilmIOR) > 0},
+	}
+	for _, f := range m.principledFields(&PrincipledParams{}) {
+		fields = append(fields, field{f.name, len(f.raw) > 0})
+	}
+	ok := map[string]bool{}
+	for _, name := range allowed {
+		ok[name] = true
+	}
+	for _, f := range fields {
+		if f.given && !ok[f.name] {
+			return fmt.Errorf("%s materials don't support %q", m.Type, f.name)
+		}
+	}
+	return nil
+}
+
+// mix parses a mix of two materials by weight, 0.5 by default, or by Fresnel reflectance
+func (l *sceneLoader) mix(m materialJSON, where string) (Material, error) {
+	if err := m.only("materials", "weight", "fresnel"); err != nil {
+		return Material{}, err
+	}
+	if len(m.Materials) != 2 {
+		return Material{}, fmt.Errorf("mix needs exactly 2 materials, got %d", len(m.Materials))
+	}
+	var materials [2]Material
+	for i, raw := range m.Materials {
+		var err error
+		if materials[i], err = l.materialRef(raw, fmt.Sprintf("%s.materials[%d]", where, i)); err != nil {
+			return Material{}, fmt.Errorf("materials[%d]: %w", i, err)
+		}
+	}
+	if m.Fresnel != nil {
+		if len(m.Weight) > 0 {
+			return Material{}, fmt.Errorf("expected either \"weight\" or \"fresnel\"")
+		}
+		if *m.Fresnel <= 0 {
+			return Material{}, fmt.Errorf("fresnel has to be a positive index of refraction, got %g", *m.Fresnel)
+		}
+		return NewFresnelMix(materials[0], materials[1], *m.Fresnel), nil
+	}
+	weight := gray(0.5)
+	if len(m.Weight) > 0 {
+		var err error
+		if weight, err = l.scalarTexture(m.Weight); err != nil {
+			return Material{}, fmt.Errorf("weight: %w", err)
+		}
+	}
+	return NewMix(materials[0], materials[1], weight), nil
+}
+
+// layered parses a coat over a base material, the coat is smooth and clear with
+// index of refraction 1.5 and no thickness by default
+func (l *sceneLoader) layered(m materialJSON, where string) (Material, error) {
+	if err := m.only("base", "roughness", "ior", "thickness", "absorption"); err != nil {
+		return Material{}, err
+	}
+	if len(m.Base) == 0 {
+		return Material{}, fmt.Errorf("layered material needs \"base\"")
+	}
+	base, err := l.materialRef(m.Base, where+".base")
+	if err != nil {
+		return Material{}, fmt.Errorf("base: %w", err)
+	}
+	// roughness and ior of the coat are either numbers or textures, which become maps of the material
+	roughness, ior, thickness, absorption := 0.0, 1.5, 0.0, Color{0, 0, 0}
+	var maps MaterialMaps
+	for _, field := range []struct {
+		name    string
+		raw     json.RawMessage
+		v       *float64
+		texture **Texture
+	}{{"roughness", m.Roughness, &roughness, &maps.Roughness}, {"ior", m.IOR, &ior, &maps.IOR}} {
+		if len(field.raw) == 0 || json.Unmarshal(field.raw, field.v) == nil {
+			continue
+		}
+		texture, err := l.texture(field.raw)
+		if err != nil {
+			return Material{}, fmt.Errorf("%s: %w", field.name, err)
+		}
+		*field.texture = &texture
+	}
+	if m.Thickness != nil {
+		thickness = *m.Thickness
+	}
+	if len(m.Absorption) > 0 {
+		if absorption, err = parseColor(m.Absorption); err != nil {
+			return Material{}, fmt.Errorf("absorption: %w", err)
+		}
+	}
+	if ior <= 0 || thickness < 0 {
+		return Material{}, fmt.Errorf("ior has to be positive and thickness can't be negative")
+	}
+	material := NewLayered(base, roughness, ior, thickness, absorption)
+	if maps != (MaterialMaps{}) {
+		material = material.WithMaps(maps)
+	}
+	return material, nil
+}
+
+// opacity cuts out surfaces of the material where opacity is given
+func (l *sceneLoader) opacity(m materialJSON, material Material) (Material, error) {
+	if len(m.Opacity) == 0 {
+		if m.OpacityThreshold != nil {
+			return Material{}, fmt.Errorf("\"opacityThreshold\" needs \"opacity\"")
+		}
+		return material, nil
+	}
+	threshold := 0.0
+	if m.OpacityThreshold != nil {
+		threshold = *m.OpacityThreshold
+	}
+	var opacity Texture
+	var name string
+	if json.Unmarshal(m.Opacity, &name) == nil && name == "alpha" {
+		albedo := m.Albedo
+		if m.Type == "principled" {
+			albedo = m.BaseColor
+		}
+		var t textureJSON
+		if err := decodeStrict(albedo, &t); err != nil || t.Type != "image" {
+			return Material{}, fmt.Errorf("opacity: \"alpha\" needs an image texture as albedo")
+		}
+		alpha, err := getAlpha(l.path(t.Path), &l.scene.imageArray)
+		if err != nil {
+			return Material{}, fmt.Errorf("opacity: %w", err)
+		}
+		if alpha == nil {
+			return Material{}, fmt.Errorf("opacity: %q has no alpha channel", t.Path)
+		}
+		opacity = NewImageUV(alpha)
+	} else {
+		var err error
+		if opacity, err = l.scalarTexture(m.Opacity); err != nil {
+			return Material{}, fmt.Errorf("opacity: %w", err)
+		}
+	}
+	return material.WithOpacity(opacity, threshold), nil
+}
+
+// scalarTexture parses a texture given as a number, a color or a texture object,
+// scalar parameters use the red channel of the texture
+func (l *sceneLoader) scalarTexture(raw json.RawMessage) (Texture, error) {
+	var v float64
+	if err := json.Unmarshal(raw, &v); err == nil {
+		return gray(v), nil
+	}
+	return l.texture(raw)
+}
+
+// texture parses a texture given either as a color or as a texture object
+func (l *sceneLoader) texture(raw json.RawMessage) (Texture, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && trimmed[0] != '{' {
+		c, err := parseColor(raw)
+		if err != nil {
+			return Texture{}, err
+		}
+		return NewConstant(c), nil
+	}
+
+	var t textureJSON
+	if err := decodeStrict(raw, &t); err != nil {
+		return Texture{}, err
+	}
+
+	colors := make([]Color, len(t.Colors))
+	for i, c := range t.Colors {
+		var err error
+		if colors[i], err = parseColor(c); err != nil {
+			return Texture{}, fmt.Errorf("colors[%d]: %w", i, err)
+		}
+	}
+	twoColors := func() error {
+		if len(colors) != 2 {
+			return fmt.Errorf("%s texture needs exactly 2 colors, got %d", t.Type, len(colors))
+		}
+		return nil
+	}
+	scale := func(n int) error {
+		if len(t.Scale) != n {
+			return fmt.Errorf("%s texture needs scale with %d values, got %d", t.Type, n, len(t.Scale))
+		}
+		for _, s := range t.Scale {
+			if s == 0 {
+				return fmt.Errorf("scale values can't be zero")
+			}
+		}
+		return nil
+	}
+
+	switch t.Type {
+	case "constant":
+		c, err := parseColor(t.Color)
+		if err != nil {
+			return Texture{}, fmt.Errorf("color: %w", err)
+		}
+		return NewConstant(c), nil
+	case "checkerboard", "grid":
+		if err := twoColors(); err != nil {
+			return Texture{}, err
+		}
+		if err := scale(3); err != nil {
+			return Texture{}, err
+		}
+		if t.Type == "grid" {
+			return NewGrid(colors[0], colors[1], t.Scale[0], t.Scale[1], t.Scale[2], t.Width), nil
+		}
+		return NewCheckerboard(colors[0], colors[1], t.Scale[0], t.Scale[1], t.Scale[2]), nil
+	case "checkerboardUV", "gridUV":
+		if err := twoColors(); err != nil {
+			return Texture{}, err
+		}
+		if err := scale(2); err != nil {
+			return Texture{}, err
+		}
+		if t.Type == "gridUV" {
+			return NewGridUV(colors[0], colors[1], t.Scale[0], t.Scale[1], t.Width), nil
+		}
+		return NewCheckerboardUV(colors[0], colors[1], t.Scale[0], t.Scale[1]), nil
+	case "image":
+		if t.Path == "" {
+			return Texture{}, fmt.Errorf("image texture needs \"path\"")
+		}
+		diffuse, err := l.image(t.Path)
+		if err != nil {
+			return Texture{}, fmt.Errorf("path: %w", err)
+		}
+		if t.Normal == "" {
+			return NewImageUV(diffuse), nil
+		}
+		normal, err := l.image(t.Normal)
+		if err != nil {
+			return Texture{}, fmt.Errorf("normal: %w", err)
+		}
+		return NewDiffNormalUV(diffuse, normal), nil
+	case "":
+		return Texture{}, fmt.Errorf("missing \"type\"")
+	default:
+		return Texture{}, fmt.Errorf("unknown texture type %q", t.Type)
+	}
+}
+
+func (l *sceneLoader) image(path string) ([][]Color, error) {
+	path = l.path(path)
+	if !fileExists(path) {
+		return nil, fmt.Errorf("file %q does not exist", path)
+	}
+	return getTexture(path, &l.scene.imageArray)
+}
+
+// path resolves paths relative to the directory of the scene file
+func (l *sceneLoader) path(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(l.dir, path)
+}
+
+// medium sets absorption and priority of dielectrics
+func (m *materialJSON) medium(material *Material) error {
+	if len(m.Absorption) == 0 && len(m.Transmittance) == 0 && m.TransmittanceDistance == nil && m.Priority == nil {
+		return nil
+	}
+	if material.material != BSDF {
+		return fmt.Errorf("%q materials don't support absorption and priorities", m.Type)
+	}
+	switch {
+	case len(m.Absorption) > 0 && (len(m.Transmittance) > 0 || m.TransmittanceDistance != nil):
+		return fmt.Errorf("\"absorption\" can't be used with \"transmittance\"")
+	case len(m.Absorption) > 0:
+		absorption, err := parseColor(m.Absorption)
+		if err != nil {
+			return fmt.Errorf("absorption: %w", err)
+		}
+		*material = material.WithAbsorption(absorption)
+	case len(m.Transmittance) > 0:
+		transmittance, err := parseColor(m.Transmittance)
+		if err != nil {
+			return fmt.Errorf("transmittance: %w", err)
+		}
+		distance := 1.0
+		if m.TransmittanceDistance != nil {
+			distance = *m.TransmittanceDistance
+		}
+		if distance <= 0 {
+			return fmt.Errorf("transmittanceDistance has to be positive, got %g", distance)
+		}
+		*material = material.WithTransmittance(transmittance, distance)
+	case m.TransmittanceDistance != nil:
+		return fmt.Errorf("\"transmittanceDistance\" needs \"transmittance\"")
+	}
+	if m.Priority != nil {
+		*material = material.WithPriority(*m.Priority)
+	}
+	return nil
+}
+
+// parseConductor parses a conductor given as the name of a preset, or an object with either
+// "eta" and "k" or "reflectivity" and "edgeTint" colors
+func parseConductor(raw json.RawMessage) (Conductor, error) {
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		c, ok := ConductorPreset(name)
+		if !ok {
+			return Conductor{}, fmt.Errorf("unknown conductor %q (expected one of %s)", name, strings.Join(conductorNames(), ", "))
+		}
+		return c, nil
+	}
+
+	var c conductorJSON
+	if err := decodeStrict(raw, &c); err != nil {
+		return Conductor{}, err
+	}
+	switch {
+	case len(c.Eta) > 0 && len(c.Reflectivity) == 0 && len(c.EdgeTint) == 0:
+		eta, err := parseColor(c.Eta)
+		if err != nil {
+			return Conductor{}, fmt.Errorf("eta: %w", err)
+		}
+		k, err := parseColor(c.K)
+		if err != nil {
+			return Conductor{}, fmt.Errorf("k: %w", err)
+		}
+		return Conductor{eta, k}, nil
+	case len(c.Reflectivity) > 0 && len(c.Eta) == 0 && len(c.K) == 0:
+		reflectivity, err := parseColor(c.Reflectivity)
+		if err != nil {
+			return Conductor{}, fmt.Errorf("reflectivity: %w", err)
+		}
+		edgeTint, err := parseColor(c.EdgeTint)
+		if err != nil {
+			return Conductor{}, fmt.Errorf("edgeTint: %w", err)
+		}
+		return EdgeTint(reflectivity, edgeTint), nil
+	}
+	return Conductor{}, fmt.Errorf("expected a preset name, \"eta\" and \"k\", or \"reflectivity\" and \"edgeTint\"")
+}
+
+// parseColor parses colors given as [r, g, b] or as a "#rrggbb" hex string
+func parseColor(raw json.RawMessage) (Color, error) {
+	if len(raw) == 0 {
+		return Color{}, fmt.Errorf("missing color")
+	}
+	var hex string
+	if err := json.Unmarshal(raw, &hex); err == nil {
+		value, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 24)
+		if err != nil || len(strings.TrimPrefix(hex, "#")) != 6 {
+			return Color{}, fmt.Errorf("invalid hex color %q (expected \"#rrggbb\")", hex)
+		}
+		return Hex(int(value)), nil
+	}
+	var values []float64
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return Color{}, fmt.Errorf("invalid color %s (expected [r, g, b] or \"#rrggbb\")", string(raw))
+	}
+	if len(values) != 3 {
+		return Color{}, fmt.Errorf("color needs 3 values, got %d", len(values))
+	}
+	return Color{values[0], values[1], values[2]}, nil
+}
+
+func vec3(values []float64, name string) (Tuple, error) {
+	if values == nil {
+		return Tuple{}, fmt.Errorf("missing %q", name)
+	}
+	if len(values) != 3 {
+		return Tuple{}, fmt.Errorf("%q needs 3 values, got %d", name, len(values))
+	}
+	return Tuple{values[0], values[1], values[2], 0}, nil
+}
@@ -0,0 +1,58 @@
+package pt
+
+import "math"
+
+type Sphere struct {
+	origin   Tuple
+	radius   float64
+	material Material
+}
+
+func (s *Sphere) boundingBox() AABB {
+	r := Tuple{s.radius, s.radius, s.radius, 0}
+	return AABB{s.origin.Subtract(r), s.origin.Add(r)}
+}
+
+func (s Sphere) uv(p Tuple) (float64, float64) {
+	d := s.origin.Subtract(p).Normalize()
+	u := 0.5 - (math.Atan2(d.z, d.x))/(2*math.Pi)
+	v := 0.5 + (math.Asin(d.y))/(math.Pi)
+	return u, v
+}
+
+func (s *Sphere) hit(r Ray, tMin, tMax float64, rec *HitRecord) bool {
+	oc := r.origin.Subtract(s.origin)
+	a := r.direction.Dot(r.direction)
+	b := 2.0 * oc.Dot(r.direction)
+	c := oc.Dot(oc) - s.radius*s.radius
+	discriminant := b*b - 4*a*c
+	if discriminant > 0.0 {
+		// the far intersection is visible where the near one is cut out
+		for _, temp := range [2]float64{(-b - math.Sqrt(discriminant)) / (2.0 * a), (-b + math.Sqrt(discriminant)) / (2.0 * a)} {
+			if temp >= tMax || temp <= tMin {
+				continue
+			}
+			p := r.Position(temp)
+			u, v := s.uv(p)
+			// rec holds the closest hit so far, so it can't be changed by surfaces which are cut out
+			if s.material.cutout(r, HitRecord{p: p, t: temp, u: u, v: v, uT: u, vT: v, key: rec.key}) {
+				continue
+			}
+			*&rec.t = temp
+			*&rec.p = p
+			*&rec.normal = (rec.p.Subtract(s.origin)).DivScalar(s.radius).Normalize()
+			rec.geometric, rec.sphere = rec.normal, s
+			*&rec.u, *&rec.v = u, v
+			*&rec.uT, *&rec.vT = u, v
+			// texture coordinate u grows eastwards around the y axis
+			rec.tangent = Tuple{rec.normal.z, 0, -rec.normal.x, 0}
+			if len(s.material.albedo.normalTexture) > 0 {
+				uvw := rec.frame()
+				*&rec.normal = uvw.local(s.material.albedo.normal(*rec)).Normalize()
+			}
+			*&rec.material = s.material
+			return true
+		}
+	}
+	return false
+}
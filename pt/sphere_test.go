@@ -0,0 +1,74 @@
+package pt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSphereHit(t *testing.T) {
+	s := Sphere{origin: Tuple{0, 0, 5, 0}, radius: 1}
+	tests := []struct {
+		name   string
+		ray    Ray
+		tMax   float64
+		hit    bool
+		t      float64
+		normal Tuple
+	}{
+		{"front", Ray{Tuple{0, 0, 0, 0}, Tuple{0, 0, 1, 0}}, math.MaxFloat64, true, 4, Tuple{0, 0, -1, 0}},
+		{"unnormalized direction", Ray{Tuple{0, 0, 0, 0}, Tuple{0, 0, 2, 0}}, math.MaxFloat64, true, 2, Tuple{0, 0, -1, 0}},
+		{"from inside", Ray{Tuple{0, 0, 5, 0}, Tuple{1, 0, 0, 0}}, math.MaxFloat64, true, 1, Tuple{1, 0, 0, 0}},
+		{"tangent", Ray{Tuple{1, 0, 0, 0}, Tuple{0, 0, 1, 0}}, math.MaxFloat64, false, 0, Tuple{}},
+		{"miss", Ray{Tuple{0, 2, 0, 0}, Tuple{0, 0, 1, 0}}, math.MaxFloat64, false, 0, Tuple{}},
+		{"behind", Ray{Tuple{0, 0, 10, 0}, Tuple{0, 0, 1, 0}}, math.MaxFloat64, false, 0, Tuple{}},
+		{"beyond tMax", Ray{Tuple{0, 0, 0, 0}, Tuple{0, 0, 1, 0}}, 3, false, 0, Tuple{}},
+		{"far side within tMax", Ray{Tuple{0, 0, 0, 0}, Tuple{0, 0, 1, 0}}, 7, true, 4, Tuple{0, 0, -1, 0}},
+	}
+	for _, test := range tests {
+		rec := HitRecord{}
+		hit := s.hit(test.ray, Epsilon, test.tMax, &rec)
+		if hit != test.hit {
+			t.Errorf("%s: got hit %v, expected %v", test.name, hit, test.hit)
+			continue
+		}
+		if !hit {
+			continue
+		}
+		if !Equal(rec.t, test.t) {
+			t.Errorf("%s: got t %v, expected %v", test.name, rec.t, test.t)
+		}
+		if !rec.normal.Equals(test.normal) {
+			t.Errorf("%s: got normal %v, expected %v", test.name, rec.normal, test.normal)
+		}
+	}
+}
+
+func TestSphereUV(t *testing.T) {
+	s := Sphere{origin: Tuple{0, 0, 0, 0}, radius: 2}
+	for _, p := range []Tuple{{2, 0, 0, 0}, {0, 2, 0, 0}, {0, 0, -2, 0}, {1, 1, 1, 0}} {
+		u, v := s.uv(p)
+		if u < 0 || u > 1 || v < 0 || v > 1 {
+			t.Errorf("uv of %v is (%v, %v), expected values between 0 and 1", p, u, v)
+		}
+	}
+}
+
+func TestSphereTangent(t *testing.T) {
+	s := Sphere{origin: Tuple{1, 2, 3, 0}, radius: 2}
+	for _, d := range []Tuple{{1, 0, 0, 0}, {0, 0.5, -1, 0}, {-1, -1, 1, 0}, {0.3, 0.9, 0.2, 0}} {
+		d = d.Normalize()
+		rec := HitRecord{}
+		if !s.hit(Ray{s.origin.Add(d.MulScalar(5)), d.Negate()}, Epsilon, math.MaxFloat64, &rec) {
+			t.Fatalf("ray towards the center from %v didn't hit the sphere", d)
+		}
+		if !Equal(rec.tangent.Dot(rec.normal), 0) {
+			t.Errorf("tangent %v isn't perpendicular to normal %v", rec.tangent, rec.normal)
+		}
+		// moving along the tangent has to increase u
+		u, _ := s.uv(rec.p)
+		step, _ := s.uv(rec.p.Add(rec.tangent.Normalize().MulScalar(1e-4)))
+		if step <= u {
+			t.Errorf("u decreases from %v to %v along tangent %v at %v", u, step, rec.tangent, rec.p)
+		}
+	}
+}
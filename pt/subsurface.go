@@ -0,0 +1,115 @@
+package pt
+
+import "math"
+
+// maxWalkSteps limits the number of scattering events of random walks, light still inside after
+// them is lost
+const maxWalkSteps = 256
+
+// NewSubsurface returns a translucent material such as skin, wax, marble or milk. Light enters and
+// leaves it through a dielectric surface with roughness and ior and scatters inside, albedo is its color
+// seen from far away and radius is the mean free path of light for every channel, multiplied by scale.
+// The surface reflects some light back inside, so refracting materials look darker and more saturated
+// than albedo. Objects of subsurface materials have to be closed meshes or spheres
+func NewSubsurface(albedo Texture, radius Color, scale, roughness, ior float64) Material {
+	m := NewBSDF(albedo, roughness, ior, 0, 0, 0, 0)
+	m.material, m.radius = Subsurface, radius.MulScalar(scale)
+	return m
+}
+
+// surface returns the dielectric boundary of a subsurface material, light it transmits is
+// scattered inside by randomWalk
+func (m Material) surface() Material {
+	m.material, m.albedo, m.transmission = BSDF, NewConstant(Color{1, 1, 1}), 1
+	return m
+}
+
+// scattering returns the subsurface material scattering light below the surface of m at rec,
+// principled materials scatter their base color below a surface with their roughness and ior
+func (m Material) scattering(rec HitRecord) Material {
+	if m.material != Principled {
+		return m
+	}
+	p := m.principled.at(rec)
+	return NewSubsurface(NewConstant(p.baseColor), p.scatterDistance, 1, p.roughness, p.ior).
+		WithAnisotropy(p.anisotropic, m.principled.AnisotropicRotation.value(rec))
+}
+
+// walkCoefficients returns the extinction coefficient and the single-scattering albedo of a medium
+// whose multiple scattering has the albedo for a mean free path (Chiang et al. 2016)
+func walkCoefficients(albedo, radius float64) (sigmaT, alpha float64) {
+	albedo = math.Max(0, math.Min(0.999, albedo))
+	alpha = 1 - math.Exp(albedo*(-5.09406+albedo*(2.61188-albedo*4.31805)))
+	s := 1.9 - albedo + 3.5*(albedo-0.8)*(albedo-0.8)
+	return 1 / math.Max(radius*s, 1e-16), alpha
+}
+
+// randomWalk follows light going into a subsurface material along r until it reaches the boundary
+// of the object. It returns the ray arriving at the boundary from inside, the hit of the boundary with
+// the material, whose dielectric surface refracts the light out or reflects it back inside, and the
+// throughput of the walk. ok is false if the light was lost
+func (scene *Scene) randomWalk(r Ray, rec HitRecord, sampler Sampler) (in Ray, exit HitRecord, throughput Color, ok bool) {
+	material := rec.material.scattering(rec)
+	albedo := material.albedo.color(rec)
+	var sigmaT, alpha [3]float64
+	sigmaT[0], alpha[0] = walkCoefficients(albedo.r, material.radius.r)
+	sigmaT[1], alpha[1] = walkCoefficients(albedo.g, material.radius.g)
+	sigmaT[2], alpha[2] = walkCoefficients(albedo.b, material.radius.b)
+
+	// walks are long, so they use independent random numbers seeded by the next value of the sampler
+	var walk independentSampler
+	key := mix64(uint64(sampler.Get1D() * (1 << 53)))
+	walk.rng.seed(key, mix64(key+0x9e3779b97f4a7c15))
+
+	weight := [3]float64{1, 1, 1}
+	p, direction := rec.p, r.direction.Normalize()
+	for i := 0; i < maxWalkSteps; i++ {
+		// distances are sampled for a channel chosen in proportion to its throughput and weighted by
+		// the density of all channels, so that channels with different radii are sampled well together
+		var probability [3]float64
+		total := weight[0] + weight[1] + weight[2]
+		for c := range probability {
+			probability[c] = weight[c] / total
+		}
+		channel, u := 0, walk.Get1D()
+		for channel < 2 && u >= probability[channel] {
+			u -= probability[channel]
+			channel++
+		}
+		t := -math.Log(1-walk.Get1D()) / sigmaT[channel]
+
+		hit := HitRecord{key: key}
+		boundary := scene.world.hit(Ray{p, direction}, Epsilon, t, &hit)
+		if boundary {
+			t = hit.t
+		}
+		var tr [3]float64
+		pdf := 0.0
+		for c := range tr {
+			tr[c] = math.Exp(-sigmaT[c] * t)
+			if boundary {
+				// probability of sampling a distance past the boundary
+				pdf += probability[c] * tr[c]
+			} else {
+				pdf += probability[c] * sigmaT[c] * tr[c]
+			}
+		}
+		for c := range weight {
+			if boundary {
+				weight[c] *= tr[c] / pdf
+			} else {
+				weight[c] *= alpha[c] * sigmaT[c] * tr[c] / pdf
+			}
+		}
+		if boundary {
+			hit.material = material
+			return Ray{p, direction}, hit, Color{weight[0], weight[1], weight[2]}, true
+		}
+		p = p.Add(direction.MulScalar(t))
+		direction = sampleSphere(walk.Get2D())
+		if weight[0]+weight[1]+weight[2] <= 0 {
+			break
+		}
+	}
+	return Ray{}, HitRecord{}, Color{0, 0, 0}, false
+}
@@ -0,0 +1,78 @@
+package pt
+
+import (
+	"io"
+	"log"
+	"math"
+	"os"
+	"testing"
+)
+
+// TestSubsurfaceFurnace checks that a subsurface sphere lit by a white environment reflects its albedo
+// when light doesn't get far inside it and its surface doesn't refract light, and never more light
+// than it receives. Refracting surfaces reflect some of the light back inside, where more of it is absorbed
+func TestSubsurfaceFurnace(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	albedo := Color{0.8, 0.5, 0.2}
+	opts := Options{Depth: 20}
+	sampler, _ := NewSampler("independent", 1, 0)
+	tests := []struct {
+		radius, roughness, ior float64
+		mesh, principled       bool
+	}{{0.02, 0, 1, false, false}, {0.5, 0, 1, false, false}, {0.02, 0, 1, true, false}, {0.02, 0, 1.4, false, false},
+		{0.05, 0.2, 1.4, true, false}, {0.02, 0, 1, false, true}, {0.05, 0.2, 1.4, true, true}}
+	for _, test := range tests {
+		radius := test.radius
+		scene := NewScene()
+		material := NewSubsurface(NewConstant(albedo), Color{1, 1, 1}, radius, test.roughness, test.ior)
+		if test.principled {
+			material = principledMaterial(func(p *PrincipledParams) {
+				p.BaseColor, p.ScatterDistance = NewConstant(albedo), gray(radius)
+				p.Roughness, p.IOR = gray(test.roughness), gray(test.ior)
+			})
+		}
+		if test.mesh {
+			mesh := &Mesh{triangles: [][]Triangle{meshSphere(Tuple{0, 0, 0, 0}, 1, 24, 48)}}
+			scene.AddInstance(mesh, Identity(), &material)
+		} else {
+			scene.AddSphere(Tuple{0, 0, 0, 0}, 1, material)
+		}
+		scene.SetEnvironment(NewConstant(Color{1, 1, 1}))
+		scene.buildWorld()
+		sum := Color{0, 0, 0}
+		samples := 4000
+		for i := 0; i < samples; i++ {
+			sampler.StartPixelSample(0, i)
+			sum = sum.Add(colorize(Ray{Tuple{0, 0, -5, 0}, Tuple{0, 0, 1, 0}}, scene, &opts, 0, 0, sampler, nil))
+		}
+		c := sum.DivScalar(float64(samples))
+		for _, v := range []float64{c.r, c.g, c.b} {
+			if v > 1.02 {
+				t.Errorf("radius %g (mesh %v, principled %v): sphere reflects %v of light", radius, test.mesh, test.principled, c)
+			}
+		}
+		if test.ior > 1 {
+			// the surface adds a few percent of specular reflection
+			if c.r > albedo.r+0.05 || c.g > albedo.g+0.05 || c.b > albedo.b+0.05 || c.r < c.g || c.g < c.b {
+				t.Errorf("radius %g, ior %g (mesh %v, principled %v): sphere reflects %v of light, expected less than %v", radius, test.ior, test.mesh, test.principled, c, albedo)
+			}
+			continue
+		}
+		if radius < 0.1 && (math.Abs(c.r-albedo.r) > 0.05 || math.Abs(c.g-albedo.g) > 0.05 || math.Abs(c.b-albedo.b) > 0.05) {
+			t.Errorf("radius %g (mesh %v, principled %v): sphere reflects %v of light, expected about %v", radius, test.mesh, test.principled, c, albedo)
+		}
+	}
+}
+
+func TestWalkCoefficients(t *testing.T) {
+	previous := 0.0
+	for _, albedo := range []float64{0, 0.2, 0.5, 0.8, 1} {
+		sigmaT, alpha := walkCoefficients(albedo, 0.5)
+		if alpha < previous || alpha > 1 || sigmaT <= 0 {
+			t.Errorf("albedo %g: extinction %g, single-scattering albedo %g", albedo, sigmaT, alpha)
+		}
+		previous = alpha
+	}
+}
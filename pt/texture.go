@@ -1,6 +1,7 @@
-package main
+package pt
 
 import (
+	"fmt"
 	"math"
 )
 
@@ -14,6 +15,7 @@ const (
 	TriangleImageUV
 )
 
+// Texture gives a color for every point of a surface
 type Texture struct {
 	c                             []Color
 	scaleX, scaleY, scaleZ, width float64
@@ -22,34 +24,69 @@ type Texture struct {
 	normalTexture                 [][]Color
 }
 
-func getConstant(c Color) Texture {
+// NewConstant returns a texture with a single color
+func NewConstant(c Color) Texture {
 	return Texture{[]Color{c}, 0, 0, 0, 0, Constant, nil, nil}
 }
 
-func getCheckerboard(c1, c2 Color, scaleX, scaleY, scaleZ float64) Texture {
+// gray returns a constant texture of a scalar value
+func gray(v float64) Texture {
+	return NewConstant(Color{v, v, v})
+}
+
+// NewCheckerboard returns a 3D checkerboard texture based on coordinates
+func NewCheckerboard(c1, c2 Color, scaleX, scaleY, scaleZ float64) Texture {
 	return Texture{[]Color{c1, c2}, scaleX, scaleY, scaleZ, 0, Checkerboard, nil, nil}
 }
 
-func getCheckerboardUV(c1, c2 Color, scaleU, scaleV float64) Texture {
+// NewCheckerboardUV returns a checkerboard texture based on UVs
+func NewCheckerboardUV(c1, c2 Color, scaleU, scaleV float64) Texture {
 	return Texture{[]Color{c1, c2}, scaleU, scaleV, 0, 0, CheckerboardUV, nil, nil}
 }
 
-func getGrid(c1, c2 Color, scaleX, scaleY, scaleZ, width float64) Texture {
+// NewGrid returns a 3D grid texture based on coordinates, c1 is the color of lines
+func NewGrid(c1, c2 Color, scaleX, scaleY, scaleZ, width float64) Texture {
 	return Texture{[]Color{c1, c2}, scaleX, scaleY, scaleZ, width, Grid, nil, nil}
 }
 
-func getGridUV(c1, c2 Color, scaleU, scaleV, width float64) Texture {
+// NewGridUV returns a grid texture based on UVs, c1 is the color of lines
+func NewGridUV(c1, c2 Color, scaleU, scaleV, width float64) Texture {
 	return Texture{[]Color{c1, c2}, scaleU, scaleV, 0, width, GridUV, nil, nil}
 }
 
-func getImageUV(texture [][]Color) Texture {
+// NewImageUV returns an image texture, see ReadImage
+func NewImageUV(texture [][]Color) Texture {
 	return Texture{nil, 0, 0, 0, 0, SphereImageUV, texture, nil}
 }
 
-func getDiffNormalUV(diffuse, normal [][]Color) Texture {
+// NewDiffNormalUV returns an image texture with a normal map
+func NewDiffNormalUV(diffuse, normal [][]Color) Texture {
 	return Texture{nil, 0, 0, 0, 0, SphereImageUV, diffuse, normal}
 }
 
+// String returns a short description of the texture
+func (t Texture) String() string {
+	description := "none"
+	switch t.mode {
+	case Constant:
+		if len(t.c) > 0 {
+			description = t.c[0].String()
+		}
+	case Checkerboard, CheckerboardUV:
+		description = "checkerboard"
+	case Grid, GridUV:
+		description = "grid"
+	case SphereImageUV, TriangleImageUV:
+		if len(t.diffuseTexture) > 0 {
+			description = fmt.Sprintf("image %dx%d", len(t.diffuseTexture), len(t.diffuseTexture[0]))
+		}
+	}
+	if len(t.normalTexture) > 0 {
+		description += " with normal map"
+	}
+	return description
+}
+
 func (t Texture) normal(rec HitRecord) Tuple {
 	if t.mode == SphereImageUV {
 		nx := float64(len(t.normalTexture))
@@ -91,6 +128,11 @@ func (t Texture) normal(rec HitRecord) Tuple {
 	return Tuple{pixel.r, pixel.g, pixel.b, 1}.MulScalar(2).AddScalar(-1).Normalize()
 }
 
+// value returns the scalar value of a gray texture, which is its red channel
+func (t Texture) value(rec HitRecord) float64 {
+	return t.color(rec).r
+}
+
 func (t Texture) color(rec HitRecord) Color {
 	if t.mode == Constant {
 		return t.c[0]
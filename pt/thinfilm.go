@@ -0,0 +1,110 @@
+package pt
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// thinFilm is a dielectric film on the surface of a material, like soap, oil or an oxide layer.
+// Light reflected by its top and bottom interferes, so reflectance depends on wavelength.
+// thickness is in nanometers, there's no film when it's zero
+type thinFilm struct {
+	thickness, ior float64
+}
+
+// WithThinFilm returns the material with a dielectric film of the given thickness in nanometers and
+// index of refraction ior on its surface, which colors reflections by interference like soap bubbles,
+// oil slicks or anodized and heat-tinted metals. Interference shows for thickness from about 100 to
+// 1000 nm and fades to white above it. It changes the metal, transmission and specular lobes of BSDF
+// materials, the film is on the outer side of transparent ones
+func (m Material) WithThinFilm(thickness, ior float64) Material {
+	m.film = thinFilm{thickness, ior}
+	return m
+}
+
+func (f thinFilm) String() string {
+	return fmt.Sprintf("%g nm (ior %g)", f.thickness, f.ior)
+}
+
+// filmWavelengths are wavelengths in nanometers averaged for the red, green and blue channels,
+// so interference of thick films fades to white instead of aliasing into stripes of colors
+var filmWavelengths = [3][]float64{
+	{590, 620, 650, 680},
+	{500, 525, 550, 575},
+	{420, 445, 470, 495},
+}
+
+// rgb returns reflectance of the film for light arriving at cosine cos with the normal for every
+// channel, substrate is the index of refraction of the surface below the film for the channels
+func (f thinFilm) rgb(cos float64, substrate [3]complex128) Color {
+	var c [3]float64
+	for channel, wavelengths := range filmWavelengths {
+		for _, lambda := range wavelengths {
+			c[channel] += frThinFilm(cos, lambda, f.thickness, f.ior, substrate[channel])
+		}
+		c[channel] /= float64(len(wavelengths))
+	}
+	return Color{c[0], c[1], c[2]}
+}
+
+// dielectric returns reflectance of a dielectric with relative index of refraction eta under the film,
+// cos is the cosine of the incident direction with the normal, negative below the surface like in
+// frDielectric. Without a film it's Fresnel reflectance of the dielectric
+func (f thinFilm) dielectric(cos, eta float64) Color {
+	if f.thickness <= 0 {
+		r := frDielectric(cos, eta)
+		return Color{r, r, r}
+	}
+	cos = math.Max(-1, math.Min(1, cos))
+	if cos < 0 {
+		// the film doesn't absorb light, so it reflects the same from both sides,
+		// only the direction is refracted out of the dielectric
+		sin2 := (1 - cos*cos) * eta * eta
+		if sin2 >= 1 {
+			return Color{1, 1, 1}
+		}
+		cos = math.Sqrt(1 - sin2)
+	}
+	return f.rgb(cos, [3]complex128{complex(eta, 0), complex(eta, 0), complex(eta, 0)})
+}
+
+// conductor returns reflectance of the conductor under the film for light arriving at cosine cos with the normal
+func (f thinFilm) conductor(cos float64, c Conductor) Color {
+	if f.thickness <= 0 {
+		return c.fresnel(cos)
+	}
+	return f.rgb(cos, [3]complex128{complex(c.Eta.r, c.K.r), complex(c.Eta.g, c.K.g), complex(c.Eta.b, c.K.b)})
+}
+
+// frThinFilm returns reflectance of unpolarized light of wavelength lambda on a surface with complex index
+// of refraction eta under a film of the given thickness and index of refraction filmIOR, both in the same
+// units as lambda. cosI is the cosine of the incident direction above the film with the normal. Waves
+// reflected by both sides of the film add up with the phase of the path through it (Airy summation),
+// which is exact for a single wavelength, so a spectral renderer can use it as it is
+func frThinFilm(cosI, lambda, thickness, filmIOR float64, eta complex128) float64 {
+	cosI = math.Max(0, math.Min(1, math.Abs(cosI)))
+	sin2 := complex(1-cosI*cosI, 0)
+	n1, n2, n3 := complex(1, 0), complex(filmIOR, 0), eta
+
+	// n cos of the refracted direction in every medium, imaginary where light doesn't propagate,
+	// the square roots have non-negative imaginary parts, so waves decay inside conductors
+	nc1, nc2, nc3 := complex(cosI, 0), cmplx.Sqrt(n2*n2-sin2), cmplx.Sqrt(n3*n3-sin2)
+	// amplitudes reflected at an interface for light polarized perpendicular and parallel to the plane of incidence
+	perpendicular := func(nci, nct complex128) complex128 {
+		return (nci - nct) / (nci + nct)
+	}
+	parallel := func(ni, nci, nt, nct complex128) complex128 {
+		return (nt*nt*nci - ni*ni*nct) / (nt*nt*nci + ni*ni*nct)
+	}
+
+	// phase difference of light reflected at the bottom of the film after crossing it twice
+	phase := cmplx.Exp(complex(0, 4*math.Pi*thickness/lambda) * nc2)
+	airy := func(r12, r23 complex128) float64 {
+		r := (r12 + r23*phase) / (1 + r12*r23*phase)
+		return real(r)*real(r) + imag(r)*imag(r)
+	}
+	s := airy(perpendicular(nc1, nc2), perpendicular(nc2, nc3))
+	p := airy(parallel(n1, nc1, n2, nc2), parallel(n2, nc2, n3, nc3))
+	return math.Min(1, (s+p)/2)
+}
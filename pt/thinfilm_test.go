@@ -0,0 +1,63 @@
+package pt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFrThinFilm(t *testing.T) {
+	gold := conductors["gold"]
+	for _, cos := range []float64{1, 0.8, 0.5, 0.2, 0.01} {
+		// without thickness the film disappears
+		if f, expected := frThinFilm(cos, 550, 0, 1.33, complex(1.5, 0)), frDielectric(cos, 1.5); math.Abs(f-expected) > 1e-9 {
+			t.Errorf("reflectance %v at cosine %v without thickness, expected %v", f, cos, expected)
+		}
+		if f, expected := frThinFilm(cos, 550, 0, 1.33, complex(gold.Eta.g, gold.K.g)), frConductor(cos, gold.Eta.g, gold.K.g); math.Abs(f-expected) > 1e-9 {
+			t.Errorf("reflectance %v of gold at cosine %v without thickness, expected %v", f, cos, expected)
+		}
+		// a film of the same material as the surface below it is its top layer
+		if f, expected := frThinFilm(cos, 550, 300, 1.5, complex(1.5, 0)), frDielectric(cos, 1.5); math.Abs(f-expected) > 1e-9 {
+			t.Errorf("reflectance %v at cosine %v under the same film, expected %v", f, cos, expected)
+		}
+	}
+	// a quarter wave film with the geometric mean of indices of refraction doesn't reflect light
+	ior := math.Sqrt(1.5)
+	if f := frThinFilm(1, 550, 550/(4*ior), ior, complex(1.5, 0)); f > 1e-9 {
+		t.Errorf("antireflective film reflects %v", f)
+	}
+	// and a half wave film reflects like no film at all
+	if f, expected := frThinFilm(1, 550, 550/(2*ior), ior, complex(1.5, 0)), frDielectric(1, 1.5); math.Abs(f-expected) > 1e-9 {
+		t.Errorf("half wave film reflects %v, expected %v", f, expected)
+	}
+}
+
+// TestThinFilmColors checks that reflectance of films changes with thickness differently for every
+// channel and that films reflect the same from both sides of a dielectric
+func TestThinFilmColors(t *testing.T) {
+	bubble := thinFilm{300, 1.33}
+	f := bubble.dielectric(0.9, 1)
+	if math.Abs(f.r-f.g) < 0.01 && math.Abs(f.g-f.b) < 0.01 {
+		t.Errorf("soap bubble reflects %v, expected a color", f)
+	}
+	for _, c := range []float64{f.r, f.g, f.b} {
+		if c < 0 || c > 1 {
+			t.Errorf("soap bubble reflects %v", f)
+		}
+	}
+	oil := thinFilm{400, 1.47}
+	for _, cos := range []float64{1, 0.7, 0.2} {
+		// light leaving glass at cosine cos arrives at the film from the angle refracted out of it
+		inside := -math.Sqrt(1 - (1-cos*cos)/(1.5*1.5))
+		if above, below := oil.dielectric(cos, 1.5), oil.dielectric(inside, 1.5); !colorsEqual(above, below) {
+			t.Errorf("film reflects %v above glass and %v below it at cosine %v", above, below, cos)
+		}
+	}
+	// without a film reflectance is gray
+	if f, r := (thinFilm{0, 1.33}).dielectric(0.5, 1.5), frDielectric(0.5, 1.5); f != (Color{r, r, r}) {
+		t.Errorf("reflectance %v without film, expected %v", f, r)
+	}
+}
+
+func colorsEqual(a, b Color) bool {
+	return Equal(a.r, b.r) && Equal(a.g, b.g) && Equal(a.b, b.b)
+}
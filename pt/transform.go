@@ -0,0 +1,258 @@
+package pt
+
+import (
+	"fmt"
+	"math"
+)
+
+// Transform is an affine transformation stored as a 4x4 matrix together with its inverse.
+// Points, vectors and normals are transformed differently, see Point, Vector and Normal
+type Transform struct {
+	m, inv [4][4]float64
+}
+
+// Identity returns a transformation which doesn't change anything
+func Identity() Transform {
+	return Transform{identity4(), identity4()}
+}
+
+func identity4() [4][4]float64 {
+	return [4][4]float64{
+		{1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1},
+	}
+}
+
+// NewTransform returns a transformation with the given matrix, it fails if the matrix can't be inverted
+func NewTransform(m [4][4]float64) (Transform, error) {
+	inv, ok := invert4(m)
+	if !ok {
+		return Transform{}, fmt.Errorf("matrix can't be inverted")
+	}
+	return Transform{m, inv}, nil
+}
+
+// Translate returns a translation by x, y, z
+func Translate(x, y, z float64) Transform {
+	t := Identity()
+	t.m[0][3], t.m[1][3], t.m[2][3] = x, y, z
+	t.inv[0][3], t.inv[1][3], t.inv[2][3] = -x, -y, -z
+	return t
+}
+
+// Scale returns scaling by x, y, z, none of them can be zero
+func Scale(x, y, z float64) Transform {
+	t := Identity()
+	t.m[0][0], t.m[1][1], t.m[2][2] = x, y, z
+	t.inv[0][0], t.inv[1][1], t.inv[2][2] = 1/x, 1/y, 1/z
+	return t
+}
+
+// RotateX returns rotation around X axis by angle in radians, counterclockwise
+// when looking from positive X towards the origin
+func RotateX(angle float64) Transform {
+	return AxisAngle(Tuple{1, 0, 0, 0}, angle)
+}
+
+// RotateY returns rotation around Y axis by angle in radians, counterclockwise
+// when looking from positive Y towards the origin
+func RotateY(angle float64) Transform {
+	return AxisAngle(Tuple{0, 1, 0, 0}, angle)
+}
+
+// RotateZ returns rotation around Z axis by angle in radians, counterclockwise
+// when looking from positive Z towards the origin
+func RotateZ(angle float64) Transform {
+	return AxisAngle(Tuple{0, 0, 1, 0}, angle)
+}
+
+// AxisAngle returns rotation by angle in radians around the axis going through the origin
+func AxisAngle(axis Tuple, angle float64) Transform {
+	a := axis.Normalize()
+	sin, cos := math.Sincos(angle)
+	t := Identity()
+	t.m[0][0] = a.x*a.x + (1-a.x*a.x)*cos
+	t.m[0][1] = a.x*a.y*(1-cos) - a.z*sin
+	t.m[0][2] = a.x*a.z*(1-cos) + a.y*sin
+	t.m[1][0] = a.x*a.y*(1-cos) + a.z*sin
+	t.m[1][1] = a.y*a.y + (1-a.y*a.y)*cos
+	t.m[1][2] = a.y*a.z*(1-cos) - a.x*sin
+	t.m[2][0] = a.x*a.z*(1-cos) - a.y*sin
+	t.m[2][1] = a.y*a.z*(1-cos) + a.x*sin
+	t.m[2][2] = a.z*a.z + (1-a.z*a.z)*cos
+	// rotation matrices are orthogonal, so the inverse is the transpose
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			t.inv[i][j] = t.m[j][i]
+		}
+	}
+	return t
+}
+
+// Shear returns shearing, xy moves x in proportion to y and so on. It fails if the shearing
+// flattens space, like xy = yx = 1
+func Shear(xy, xz, yx, yz, zx, zy float64) (Transform, error) {
+	t, err := NewTransform(shearMatrix(xy, xz, yx, yz, zx, zy))
+	if err != nil {
+		return Transform{}, fmt.Errorf("shear can't be inverted")
+	}
+	return t, nil
+}
+
+func shearMatrix(xy, xz, yx, yz, zx, zy float64) [4][4]float64 {
+	m := identity4()
+	m[0][1], m[0][2], m[1][0], m[1][2], m[2][0], m[2][1] = xy, xz, yx, yz, zx, zy
+	return m
+}
+
+// LookAt returns a transformation which places an object at from, with its -Z axis
+// pointing at to and Y axis towards up, like the camera. It fails if from and to are
+// the same point or up is parallel to the direction between them
+func LookAt(from, to, up Tuple) (Transform, error) {
+	if from.Equals(to) {
+		return Transform{}, fmt.Errorf("\"from\" and \"to\" can't be the same point")
+	}
+	w := from.Subtract(to).Normalize()
+	u := up.Cross(w)
+	if u.Magnitude() < Epsilon*up.Magnitude() || up.Magnitude() == 0 {
+		return Transform{}, fmt.Errorf("\"up\" can't be zero or parallel to the direction from \"from\" to \"to\"")
+	}
+	u = u.Normalize()
+	v := w.Cross(u)
+	t := Identity()
+	for i, axis := range []Tuple{u, v, w} {
+		t.m[0][i], t.m[1][i], t.m[2][i] = axis.x, axis.y, axis.z
+		t.inv[i][0], t.inv[i][1], t.inv[i][2] = axis.x, axis.y, axis.z
+		t.inv[i][3] = -axis.Dot(from)
+	}
+	t.m[0][3], t.m[1][3], t.m[2][3] = from.x, from.y, from.z
+	return t, nil
+}
+
+// Mul returns transformation which applies u first and then t
+func (t Transform) Mul(u Transform) Transform {
+	return Transform{mul4(t.m, u.m), mul4(u.inv, t.inv)}
+}
+
+// Then returns transformation which applies t first and then u
+func (t Transform) Then(u Transform) Transform {
+	return u.Mul(t)
+}
+
+// Inverse returns the inverse transformation
+func (t Transform) Inverse() Transform {
+	return Transform{t.inv, t.m}
+}
+
+// Matrix returns the transformation matrix
+func (t Transform) Matrix() [4][4]float64 {
+	return t.m
+}
+
+// Point transforms a point, translation is applied
+func (t Transform) Point(p Tuple) Tuple {
+	m := &t.m
+	return Tuple{
+		m[0][0]*p.x + m[0][1]*p.y + m[0][2]*p.z + m[0][3],
+		m[1][0]*p.x + m[1][1]*p.y + m[1][2]*p.z + m[1][3],
+		m[2][0]*p.x + m[2][1]*p.y + m[2][2]*p.z + m[2][3],
+		p.w,
+	}
+}
+
+// Vector transforms a direction, translation isn't applied
+func (t Transform) Vector(v Tuple) Tuple {
+	m := &t.m
+	return Tuple{
+		m[0][0]*v.x + m[0][1]*v.y + m[0][2]*v.z,
+		m[1][0]*v.x + m[1][1]*v.y + m[1][2]*v.z,
+		m[2][0]*v.x + m[2][1]*v.y + m[2][2]*v.z,
+		v.w,
+	}
+}
+
+// Normal transforms a surface normal using the inverse transpose, so it stays perpendicular
+// to the transformed surface under non-uniform scaling. The result isn't normalized
+func (t Transform) Normal(n Tuple) Tuple {
+	inv := &t.inv
+	return Tuple{
+		inv[0][0]*n.x + inv[1][0]*n.y + inv[2][0]*n.z,
+		inv[0][1]*n.x + inv[1][1]*n.y + inv[2][1]*n.z,
+		inv[0][2]*n.x + inv[1][2]*n.y + inv[2][2]*n.z,
+		n.w,
+	}
+}
+
+// ray transforms a ray, its direction isn't normalized so distances along it stay the same
+func (t Transform) ray(r Ray) Ray {
+	return Ray{t.Point(r.origin), t.Vector(r.direction)}
+}
+
+// box returns a box enclosing the transformed box
+func (t Transform) box(b AABB) AABB {
+	box := emptyBox()
+	for i := 0; i < 8; i++ {
+		corner := b.min
+		if i&1 != 0 {
+			corner.x = b.max.x
+		}
+		if i&2 != 0 {
+			corner.y = b.max.y
+		}
+		if i&4 != 0 {
+			corner.z = b.max.z
+		}
+		p := t.Point(corner)
+		box = box.union(AABB{p, p})
+	}
+	return box
+}
+
+func mul4(a, b [4][4]float64) [4][4]float64 {
+	var m [4][4]float64
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			for k := 0; k < 4; k++ {
+				m[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return m
+}
+
+// invert4 inverts a matrix using Gauss-Jordan elimination with partial pivoting
+func invert4(m [4][4]float64) ([4][4]float64, bool) {
+	inv := identity4()
+	for col := 0; col < 4; col++ {
+		pivot := col
+		for row := col + 1; row < 4; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return inv, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+
+		scale := 1 / m[col][col]
+		for j := 0; j < 4; j++ {
+			m[col][j] *= scale
+			inv[col][j] *= scale
+		}
+		for row := 0; row < 4; row++ {
+			if row == col {
+				continue
+			}
+			f := m[row][col]
+			for j := 0; j < 4; j++ {
+				m[row][j] -= f * m[col][j]
+				inv[row][j] -= f * inv[col][j]
+			}
+		}
+	}
+	return inv, true
+}
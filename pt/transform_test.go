@@ -0,0 +1,104 @@
+package pt
+
+import (
+	"math"
+	"testing"
+)
+
+// must returns the transformation of a constructor which can fail
+func must(transform Transform, err error) Transform {
+	if err != nil {
+		panic(err)
+	}
+	return transform
+}
+
+func TestTransformInverse(t *testing.T) {
+	transforms := map[string]Transform{
+		"translate": Translate(1, -2, 3),
+		"scale":     Scale(2, 0.5, -3),
+		"rotate":    RotateX(0.3).Then(RotateY(1.1)).Then(RotateZ(-0.4)),
+		"axisAngle": AxisAngle(Tuple{1, 2, 3, 0}, 0.8),
+		"shear":     must(Shear(0.5, 0, 0.2, 0, 0, 0.1)),
+		"lookAt":    must(LookAt(Tuple{1, 2, 3, 0}, Tuple{-1, 0, 2, 0}, Tuple{0, 1, 0, 0})),
+		"composed":  Scale(4, 1, 2).Then(RotateY(0.7)).Then(Translate(1, 2, -3)),
+	}
+	p := Tuple{0.3, -1.2, 2.5, 0}
+	for name, transform := range transforms {
+		if got := transform.Inverse().Point(transform.Point(p)); !got.Equals(p) {
+			t.Errorf("%s: inverse of transformed point is %v, expected %v", name, got, p)
+		}
+		inv, ok := invert4(transform.m)
+		if !ok {
+			t.Errorf("%s: matrix can't be inverted", name)
+			continue
+		}
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				if !Equal(inv[i][j], transform.inv[i][j]) {
+					t.Fatalf("%s: cached inverse %v differs from computed %v", name, transform.inv, inv)
+				}
+			}
+		}
+	}
+}
+
+func TestTransformRotationDirection(t *testing.T) {
+	x, y, z := Tuple{1, 0, 0, 0}, Tuple{0, 1, 0, 0}, Tuple{0, 0, 1, 0}
+	if got := RotateX(math.Pi / 2).Vector(y); !got.Equals(z) {
+		t.Errorf("RotateX rotates y to %v, expected z", got)
+	}
+	if got := RotateY(math.Pi / 2).Vector(z); !got.Equals(x) {
+		t.Errorf("RotateY rotates z to %v, expected x", got)
+	}
+	if got := RotateZ(math.Pi / 2).Vector(x); !got.Equals(y) {
+		t.Errorf("RotateZ rotates x to %v, expected y", got)
+	}
+}
+
+// TestTransformNormal checks that normals stay perpendicular to surfaces under non-uniform scaling
+func TestTransformNormal(t *testing.T) {
+	transform := Scale(1, 4, 0.5).Then(RotateZ(0.3)).Then(Translate(5, 0, 0))
+	normal := Tuple{1, 1, 0, 0}.Normalize()
+	tangent := Tuple{1, -1, 0, 0}
+	got := transform.Normal(normal)
+	if dot := got.Dot(transform.Vector(tangent)); math.Abs(dot) > Epsilon {
+		t.Errorf("transformed normal isn't perpendicular to the surface, dot product is %v", dot)
+	}
+	if got := transform.Vector(normal); math.Abs(got.Dot(transform.Vector(tangent))) < Epsilon {
+		t.Errorf("normal transformed like a vector stays perpendicular, the test transformation is too simple")
+	}
+}
+
+func TestLookAt(t *testing.T) {
+	from, to := Tuple{1, 2, 3, 0}, Tuple{4, 2, 3, 0}
+	transform, err := LookAt(from, to, Tuple{0, 1, 0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := transform.Point(Tuple{0, 0, 0, 0}); !got.Equals(from) {
+		t.Errorf("origin is placed at %v, expected %v", got, from)
+	}
+	if got := transform.Vector(Tuple{0, 0, -1, 0}); !got.Equals(Tuple{1, 0, 0, 0}) {
+		t.Errorf("-Z axis points to %v, expected (1, 0, 0)", got)
+	}
+	if got := transform.Vector(Tuple{0, 1, 0, 0}); !got.Equals(Tuple{0, 1, 0, 0}) {
+		t.Errorf("Y axis points to %v, expected (0, 1, 0)", got)
+	}
+}
+
+// TestDegenerateTransforms checks that transformations which would flatten space or have
+// no orientation are rejected
+func TestDegenerateTransforms(t *testing.T) {
+	if _, err := Shear(1, 0, 1, 0, 0, 0); err == nil {
+		t.Error("shear flattening x and y is accepted")
+	}
+	from := Tuple{1, 2, 3, 0}
+	for _, test := range []struct {
+		to, up Tuple
+	}{{Tuple{1, 5, 3, 0}, Tuple{0, 1, 0, 0}}, {Tuple{1, -5, 3, 0}, Tuple{0, 2, 0, 0}}, {from, Tuple{0, 1, 0, 0}}, {Tuple{0, 0, 0, 0}, Tuple{0, 0, 0, 0}}} {
+		if _, err := LookAt(from, test.to, test.up); err == nil {
+			t.Errorf("look at %v from %v with up %v is accepted", test.to, from, test.up)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package pt
+
+import "math"
+
+type TrianglePosition struct {
+	vertex0, vertex1, vertex2 Tuple
+}
+
+type Triangle struct {
+	position TrianglePosition
+	vtexture TrianglePosition
+	vnormals TrianglePosition
+	// vtangents are tangents of vertices used with smooth normals, see smoothTangents
+	vtangents TrianglePosition
+	material  Material
+	// normal is the shading normal of flat triangles, the average of vertex normals if they're given,
+	// geometric is the normal of the plane of the triangle, used for light sampling
+	normal    Tuple
+	geometric Tuple
+	smooth    bool
+}
+
+func (tri *Triangle) boundingBox() AABB {
+	v0, v1, v2 := tri.position.vertex0, tri.position.vertex1, tri.position.vertex2
+	return AABB{
+		Tuple{min3(v0.x, v1.x, v2.x), min3(v0.y, v1.y, v2.y), min3(v0.z, v1.z, v2.z), 0},
+		Tuple{max3(v0.x, v1.x, v2.x), max3(v0.y, v1.y, v2.y), max3(v0.z, v1.z, v2.z), 0},
+	}
+}
+
+func (tri *Triangle) hit(r Ray, tMin, tMax float64, rec *HitRecord) bool {
+	vertex0 := tri.position.vertex0
+	vertex1 := tri.position.vertex1
+	vertex2 := tri.position.vertex2
+	edge1 := vertex1.Subtract(vertex0)
+	edge2 := vertex2.Subtract(vertex0)
+	h := r.direction.Cross(edge2)
+	a := edge1.Dot(h)
+	if a > -Epsilon && a < Epsilon {
+		return false
+	}
+	f := 1.0 / a
+	s := r.origin.Subtract(vertex0)
+	u := f * s.Dot(h)
+	if u < 0.0 || u > 1.0 {
+		return false
+	}
+	q := s.Cross(edge1)
+	v := f * r.direction.Dot(q)
+	if v < 0.0 || u+v > 1.0 {
+		return false
+	}
+	t := f * edge2.Dot(q)
+	if t < tMax && t > tMin {
+		// texture coordinates are needed even if the triangle's material doesn't use them,
+		// materials of instances can override it
+		vt1 := tri.vtexture.vertex0
+		vt2 := tri.vtexture.vertex1
+		vt3 := tri.vtexture.vertex2
+		x := vt2.MulScalar(u).Add(vt3.MulScalar(v)).Add(vt1.MulScalar(1 - u - v))
+		p := r.origin.Add(r.direction.MulScalar(t))
+		// rec holds the closest hit so far, so it can't be changed by surfaces which are cut out
+		if tri.material.cutout(r, HitRecord{p: p, t: t, u: u, v: v, uT: x.x, vT: x.y, key: rec.key}) {
+			return false
+		}
+
+		*&rec.p = p
+		*&rec.t = t
+		*&rec.material = tri.material
+		*&rec.u = u
+		*&rec.v = v
+		rec.geometric, rec.sphere = tri.geometric, nil
+		*&rec.uT = x.x
+		*&rec.vT = x.y
+
+		if tri.smooth {
+			vn1 := tri.vnormals.vertex0
+			vn2 := tri.vnormals.vertex1
+			vn3 := tri.vnormals.vertex2
+			*&rec.normal = vn2.MulScalar(u).Add(vn3.MulScalar(v)).Add(vn1.MulScalar(1 - u - v))
+		} else {
+			*&rec.normal = tri.normal
+		}
+		if tri.smooth && tri.vtangents != (TrianglePosition{}) {
+			vt1 := tri.vtangents.vertex0
+			vt2 := tri.vtangents.vertex1
+			vt3 := tri.vtangents.vertex2
+			rec.tangent = vt2.MulScalar(u).Add(vt3.MulScalar(v)).Add(vt1.MulScalar(1 - u - v))
+		} else {
+			rec.tangent = tri.tangent()
+		}
+
+		rec.interpolated = rec.normal
+		if len(tri.material.albedo.normalTexture) > 0 {
+			uvw := rec.frame()
+			*&rec.normal = uvw.local(tri.material.albedo.normal(*rec))
+		}
+		return true
+	}
+	return false
+}
+
+// tangent returns the direction in which texture coordinate u grows on the triangle, or its
+// first edge when texture coordinates are degenerate
+func (tri *Triangle) tangent() Tuple {
+	edge1 := tri.position.vertex1.Subtract(tri.position.vertex0)
+	edge2 := tri.position.vertex2.Subtract(tri.position.vertex0)
+	uv1 := tri.vtexture.vertex1.Subtract(tri.vtexture.vertex0)
+	uv2 := tri.vtexture.vertex2.Subtract(tri.vtexture.vertex0)
+	det := uv1.x*uv2.y - uv2.x*uv1.y
+	if math.Abs(det) < 1e-12 {
+		return edge1.Normalize()
+	}
+	return edge1.MulScalar(uv2.y).Subtract(edge2.MulScalar(uv1.y)).DivScalar(det).Normalize()
+}
+
+// smoothTangents sets tangents of vertices of smooth triangles to the average tangent of triangles
+// sharing the vertex and its normal, so that they change continuously like the normals
+func smoothTangents(triangles []Triangle) {
+	type vertex struct {
+		position, normal Tuple
+	}
+	sums := map[vertex]Tuple{}
+	for i := range triangles {
+		tri := &triangles[i]
+		if !tri.smooth {
+			continue
+		}
+		tangent := tri.tangent()
+		sums[vertex{tri.position.vertex0, tri.vnormals.vertex0}] = sums[vertex{tri.position.vertex0, tri.vnormals.vertex0}].Add(tangent)
+		sums[vertex{tri.position.vertex1, tri.vnormals.vertex1}] = sums[vertex{tri.position.vertex1, tri.vnormals.vertex1}].Add(tangent)
+		sums[vertex{tri.position.vertex2, tri.vnormals.vertex2}] = sums[vertex{tri.position.vertex2, tri.vnormals.vertex2}].Add(tangent)
+	}
+	average := func(position, normal, fallback Tuple) Tuple {
+		sum := sums[vertex{position, normal}]
+		if sum.Dot(sum) < 1e-12 {
+			return fallback
+		}
+		return sum.Normalize()
+	}
+	for i := range triangles {
+		tri := &triangles[i]
+		if !tri.smooth {
+			continue
+		}
+		tangent := tri.tangent()
+		tri.vtangents = TrianglePosition{
+			average(tri.position.vertex0, tri.vnormals.vertex0, tangent),
+			average(tri.position.vertex1, tri.vnormals.vertex1, tangent),
+			average(tri.position.vertex2, tri.vnormals.vertex2, tangent),
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package pt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTriangleHit(t *testing.T) {
+	tri := Triangle{
+		position:  TrianglePosition{Tuple{0, 0, 0, 0}, Tuple{1, 0, 0, 0}, Tuple{0, 1, 0, 0}},
+		vtexture:  TrianglePosition{Tuple{0, 0, 0, 0}, Tuple{1, 0, 0, 0}, Tuple{0, 1, 0, 0}},
+		normal:    Tuple{0, 0, 1, 0},
+		geometric: Tuple{0, 0, 1, 0},
+	}
+	tests := []struct {
+		name   string
+		ray    Ray
+		tMax   float64
+		hit    bool
+		t      float64
+		uT, vT float64
+	}{
+		{"inside", Ray{Tuple{0.25, 0.25, 2, 0}, Tuple{0, 0, -1, 0}}, math.MaxFloat64, true, 2, 0.25, 0.25},
+		{"from behind", Ray{Tuple{0.5, 0.25, -1, 0}, Tuple{0, 0, 2, 0}}, math.MaxFloat64, true, 0.5, 0.5, 0.25},
+		{"outside", Ray{Tuple{0.75, 0.75, 2, 0}, Tuple{0, 0, -1, 0}}, math.MaxFloat64, false, 0, 0, 0},
+		{"parallel", Ray{Tuple{0.25, 0.25, 1, 0}, Tuple{1, 0, 0, 0}}, math.MaxFloat64, false, 0, 0, 0},
+		{"pointing away", Ray{Tuple{0.25, 0.25, 2, 0}, Tuple{0, 0, 1, 0}}, math.MaxFloat64, false, 0, 0, 0},
+		{"beyond tMax", Ray{Tuple{0.25, 0.25, 2, 0}, Tuple{0, 0, -1, 0}}, 1.5, false, 0, 0, 0},
+	}
+	for _, test := range tests {
+		rec := HitRecord{}
+		hit := tri.hit(test.ray, Epsilon, test.tMax, &rec)
+		if hit != test.hit {
+			t.Errorf("%s: got hit %v, expected %v", test.name, hit, test.hit)
+			continue
+		}
+		if !hit {
+			continue
+		}
+		if !Equal(rec.t, test.t) {
+			t.Errorf("%s: got t %v, expected %v", test.name, rec.t, test.t)
+		}
+		if !rec.p.Equals(test.ray.Position(test.t)) {
+			t.Errorf("%s: got point %v, expected %v", test.name, rec.p, test.ray.Position(test.t))
+		}
+		if !Equal(rec.uT, test.uT) || !Equal(rec.vT, test.vT) {
+			t.Errorf("%s: got texture coordinates (%v, %v), expected (%v, %v)", test.name, rec.uT, rec.vT, test.uT, test.vT)
+		}
+		if !rec.normal.Equals(tri.normal) {
+			t.Errorf("%s: got normal %v, expected %v", test.name, rec.normal, tri.normal)
+		}
+	}
+}
+
+func TestTriangleHitSmooth(t *testing.T) {
+	n0, n1, n2 := Tuple{0, 0, 1, 0}, Tuple{1, 0, 0, 0}, Tuple{0, 1, 0, 0}
+	tri := Triangle{
+		position:  TrianglePosition{Tuple{0, 0, 0, 0}, Tuple{1, 0, 0, 0}, Tuple{0, 1, 0, 0}},
+		vnormals:  TrianglePosition{n0, n1, n2},
+		normal:    Tuple{0, 0, 1, 0},
+		geometric: Tuple{0, 0, 1, 0},
+		smooth:    true,
+	}
+	rec := HitRecord{}
+	if !tri.hit(Ray{Tuple{0.5, 0.25, 1, 0}, Tuple{0, 0, -1, 0}}, Epsilon, math.MaxFloat64, &rec) {
+		t.Fatal("ray didn't hit the triangle")
+	}
+	// barycentric coordinates of the hit are 0.25, 0.5, 0.25
+	expected := n0.MulScalar(0.25).Add(n1.MulScalar(0.5)).Add(n2.MulScalar(0.25))
+	if !rec.normal.Equals(expected) {
+		t.Errorf("got interpolated normal %v, expected %v", rec.normal, expected)
+	}
+}
+
+func TestTriangleTangent(t *testing.T) {
+	tri := Triangle{
+		position:  TrianglePosition{Tuple{0, 0, 0, 0}, Tuple{0, 2, 0, 0}, Tuple{0, 0, 2, 0}},
+		vtexture:  TrianglePosition{Tuple{0.5, 0.5, 0, 0}, Tuple{0.5, 0, 0, 0}, Tuple{0, 0.5, 0, 0}},
+		normal:    Tuple{1, 0, 0, 0},
+		geometric: Tuple{1, 0, 0, 0},
+	}
+	rec := HitRecord{}
+	if !tri.hit(Ray{Tuple{1, 0.5, 0.5, 0}, Tuple{-1, 0, 0, 0}}, Epsilon, math.MaxFloat64, &rec) {
+		t.Fatal("ray didn't hit the triangle")
+	}
+	// u decreases towards vertex 2, along z
+	if expected := (Tuple{0, 0, -1, 0}); !rec.tangent.Equals(expected) {
+		t.Errorf("got tangent %v, expected %v", rec.tangent, expected)
+	}
+
+	// without texture coordinates the first edge is used
+	tri.vtexture = TrianglePosition{}
+	if !tri.tangent().Equals(Tuple{0, 1, 0, 0}) {
+		t.Errorf("got tangent %v without texture coordinates, expected the first edge", tri.tangent())
+	}
+}
+
+func TestSmoothTangents(t *testing.T) {
+	// two triangles of a quad sharing an edge, bent along it, with texture coordinates of a plane
+	n := Tuple{0, 0, 1, 0}
+	quad := []Triangle{{
+		position: TrianglePosition{Tuple{0, 0, 0, 0}, Tuple{1, 0, 0, 0}, Tuple{0, 1, 0, 0}},
+		vtexture: TrianglePosition{Tuple{0, 0, 0, 0}, Tuple{1, 0, 0, 0}, Tuple{0, 1, 0, 0}},
+		vnormals: TrianglePosition{n, n, n},
+		smooth:   true,
+	}, {
+		position: TrianglePosition{Tuple{1, 0, 0, 0}, Tuple{1, 1, 1, 0}, Tuple{0, 1, 0, 0}},
+		vtexture: TrianglePosition{Tuple{1, 0, 0, 0}, Tuple{1, 1, 0, 0}, Tuple{0, 1, 0, 0}},
+		vnormals: TrianglePosition{n, n, n},
+		smooth:   true,
+	}}
+	smoothTangents(quad)
+	// shared vertices get the same tangent, which averages tangents of both triangles
+	if quad[0].vtangents.vertex1 != quad[1].vtangents.vertex0 || quad[0].vtangents.vertex2 != quad[1].vtangents.vertex2 {
+		t.Errorf("shared vertices have tangents %v and %v", quad[0].vtangents, quad[1].vtangents)
+	}
+	expected := quad[0].tangent().Add(quad[1].tangent()).Normalize()
+	if !quad[0].vtangents.vertex1.Equals(expected) {
+		t.Errorf("got tangent %v of a shared vertex, expected %v", quad[0].vtangents.vertex1, expected)
+	}
+	if !quad[0].vtangents.vertex0.Equals(quad[0].tangent()) {
+		t.Errorf("got tangent %v of an unshared vertex, expected %v", quad[0].vtangents.vertex0, quad[0].tangent())
+	}
+}
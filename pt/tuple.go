@@ -1,6 +1,7 @@
-package main
+package pt
 
 import (
+	"fmt"
 	"math"
 )
 
@@ -11,6 +12,27 @@ type Tuple struct {
 	//		 | 1 = point
 }
 
+// NewTuple returns a tuple with given coordinates
+func NewTuple(x, y, z float64) Tuple {
+	return Tuple{x, y, z, 0}
+}
+
+// String returns coordinates formatted as (x, y, z)
+func (v Tuple) String() string {
+	return fmt.Sprintf("(%g, %g, %g)", v.x, v.y, v.z)
+}
+
+// axis returns x, y or z coordinate for axis 0, 1 or 2
+func (v Tuple) axis(i int) float64 {
+	switch i {
+	case 0:
+		return v.x
+	case 1:
+		return v.y
+	}
+	return v.z
+}
+
 // Equals checks if two tuples are equal
 func (v Tuple) Equals(u Tuple) bool {
 	if Equal(v.x, u.x) && Equal(v.y, u.y) && Equal(v.z, u.z) && Equal(v.w, u.w) {
@@ -96,50 +118,33 @@ func (v Tuple) Mul(u Tuple) Tuple {
 
 // Translate translates tuple by x, y, z values
 func (v Tuple) Translate(x, y, z float64) Tuple {
-	transformMat := GetIdentityMatrix(4)
-	transformMat.mat[0][3], transformMat.mat[1][3], transformMat.mat[2][3] = x, y, z
-
-	return transformMat.TupMul(v)
+	return Translate(x, y, z).Point(v)
 }
 
 // Scale scales tuple by x, y, z values
 func (v Tuple) Scale(x, y, z float64) Tuple {
-	transformMat := GetIdentityMatrix(4)
-	transformMat.mat[0][0], transformMat.mat[1][1], transformMat.mat[2][2] = x, y, z
-
-	return transformMat.TupMul(v)
+	return Scale(x, y, z).Point(v)
 }
 
 // RotateX rotates tuple around X axis
 func (v Tuple) RotateX(angle float64) Tuple {
-	transformMat := GetIdentityMatrix(4)
-	transformMat.mat[1][1], transformMat.mat[2][1], transformMat.mat[2][1], transformMat.mat[2][2] = math.Cos(angle), -math.Sin(angle), math.Sin(angle), math.Cos(angle)
-
-	return transformMat.TupMul(v)
+	return RotateX(angle).Point(v)
 }
 
 // RotateY rotates tuple around Y axis
 func (v Tuple) RotateY(angle float64) Tuple {
-	transformMat := GetIdentityMatrix(4)
-	transformMat.mat[0][0], transformMat.mat[2][0], transformMat.mat[2][0], transformMat.mat[2][2] = math.Cos(angle), math.Sin(angle), -math.Sin(angle), math.Cos(angle)
-
-	return transformMat.TupMul(v)
+	return RotateY(angle).Point(v)
 }
 
 // RotateZ rotates tuple around Z axis
 func (v Tuple) RotateZ(angle float64) Tuple {
-	transformMat := GetIdentityMatrix(4)
-	transformMat.mat[0][0], transformMat.mat[0][1], transformMat.mat[1][0], transformMat.mat[1][1] = math.Cos(angle), -math.Sin(angle), math.Sin(angle), math.Cos(angle)
-
-	return transformMat.TupMul(v)
+	return RotateZ(angle).Point(v)
 }
 
 // Shear shears tuple
 func (v Tuple) Shear(xy, xz, yx, yz, zx, zy float64) Tuple {
-	transformMat := GetIdentityMatrix(4)
-	transformMat.mat[0][1], transformMat.mat[0][2], transformMat.mat[1][0], transformMat.mat[1][2], transformMat.mat[2][0], transformMat.mat[2][1] = xy, xz, yx, yz, zx, zy
-
-	return transformMat.TupMul(v)
+	// points are transformed by the matrix alone, so it doesn't need an inverse
+	return Transform{m: shearMatrix(xy, xz, yx, yz, zx, zy)}.Point(v)
 }
 
 // Reflection returns reflection vector
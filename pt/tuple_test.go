@@ -0,0 +1,73 @@
+package pt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRefraction(t *testing.T) {
+	normal := Tuple{0, 1, 0, 0}
+	var refracted Tuple
+
+	// rays along the normal aren't bent
+	if !(Tuple{0, -1, 0, 0}).Refraction(normal, 1/1.5, &refracted) {
+		t.Fatal("ray along the normal wasn't refracted")
+	}
+	if !refracted.Equals(Tuple{0, -1, 0, 0}) {
+		t.Errorf("ray along the normal was refracted to %v", refracted)
+	}
+
+	// Snell's law: sin(theta_t) = ni / nt * sin(theta_i)
+	for _, angle := range []float64{0.1, 0.5, 1, 1.4} {
+		incident := Tuple{math.Sin(angle), -math.Cos(angle), 0, 0}
+		if !incident.Refraction(normal, 1/1.5, &refracted) {
+			t.Fatalf("ray at %v rad wasn't refracted", angle)
+		}
+		refracted = refracted.Normalize()
+		sinT := math.Sqrt(1 - refracted.y*refracted.y)
+		if !Equal(sinT, math.Sin(angle)/1.5) || refracted.y > 0 {
+			t.Errorf("ray at %v rad was refracted to %v, sin of angle is %v, expected %v", angle, refracted, sinT, math.Sin(angle)/1.5)
+		}
+	}
+
+	// total internal reflection beyond the critical angle going from glass to air
+	critical := math.Asin(1 / 1.5)
+	incident := Tuple{math.Sin(critical + 0.05), -math.Cos(critical + 0.05), 0, 0}
+	if incident.Refraction(normal, 1.5, &refracted) {
+		t.Errorf("ray beyond the critical angle was refracted to %v", refracted)
+	}
+	incident = Tuple{math.Sin(critical - 0.05), -math.Cos(critical - 0.05), 0, 0}
+	if !incident.Refraction(normal, 1.5, &refracted) {
+		t.Errorf("ray below the critical angle wasn't refracted")
+	}
+}
+
+func TestFresnel(t *testing.T) {
+	normal := Tuple{0, 1, 0, 0}
+	incident := func(angle float64) Tuple {
+		return Tuple{math.Sin(angle), -math.Cos(angle), 0, 0}
+	}
+
+	if r := Fresnel(1, 1.5, normal, incident(0)); !Equal(r, 0.04) {
+		t.Errorf("reflectance at normal incidence is %v, expected 0.04", r)
+	}
+	if r := Fresnel(1.5, 1, normal, incident(0)); !Equal(r, 0.04) {
+		t.Errorf("reflectance at normal incidence from inside is %v, expected 0.04", r)
+	}
+	if r := Fresnel(1, 1.5, normal, incident(math.Pi/2)); !Equal(r, 1) {
+		t.Errorf("reflectance at grazing angle is %v, expected 1", r)
+	}
+	if r := Fresnel(1.5, 1, normal, incident(1.2)); r != 1 {
+		t.Errorf("reflectance with total internal reflection is %v, expected 1", r)
+	}
+
+	// reflectance grows with the angle of incidence
+	previous := 0.0
+	for angle := 0.0; angle < math.Pi/2; angle += 0.1 {
+		r := Fresnel(1, 1.5, normal, incident(angle))
+		if r < previous {
+			t.Errorf("reflectance at %v rad is %v, less than %v at a smaller angle", angle, r, previous)
+		}
+		previous = r
+	}
+}
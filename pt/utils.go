@@ -0,0 +1,195 @@
+package pt
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"strings"
+)
+
+type ImageHash struct {
+	image [][]Color
+	// alpha is the alpha channel as a gray image, nil if the image is opaque
+	alpha [][]Color
+	hash  string
+}
+
+func wasImageLoaded(hash string, table []ImageHash) int {
+	for i := 0; i < len(table); i++ {
+		if table[i].hash == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+type MaterialHash struct {
+	material Material
+	hash     string
+	name     string
+}
+
+func wasMaterialLoaded(hash string, table []MaterialHash) int {
+	for i := 0; i < len(table); i++ {
+		if table[i].hash == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+func hash(s string) string {
+	hasher := sha1.New()
+	hasher.Write([]byte(s))
+	sha := hasher.Sum(nil)
+	return hex.EncodeToString(sha)
+}
+
+func solveQuadratic(a, b, c float64) (float64, float64) {
+	discriminant := b*b - 4*a*c
+	if discriminant > 0 {
+		return (-b - math.Sqrt(discriminant)) / (2.0 * a), (-b + math.Sqrt(discriminant)) / (2.0 * a)
+	} else if discriminant == 0 {
+		return -b / (2.0 * a), -b / (2.0 * a)
+	}
+
+	return math.MaxFloat64, math.MaxFloat64
+}
+
+func min3(a, b, c float64) float64 {
+	if a < b {
+		if a < c {
+			return a
+		}
+		return c
+	}
+	if b < c {
+		return b
+	}
+	return c
+}
+
+func max3(a, b, c float64) float64 {
+	if a > b {
+		if a > c {
+			return a
+		}
+		return c
+	}
+	if b > c {
+		return b
+	}
+	return c
+}
+
+func loadImage(path string) (image.Image, error) {
+	log.Printf("Loading image: %s...", path)
+	textureFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer textureFile.Close()
+
+	var texture image.Image
+	if strings.HasSuffix(strings.ToLower(path), "png") {
+		texture, err = png.Decode(textureFile)
+	} else if strings.HasSuffix(strings.ToLower(path), "jpg") || strings.HasSuffix(strings.ToLower(path), "jpeg") {
+		texture, err = jpeg.Decode(textureFile)
+	} else if strings.HasSuffix(strings.ToLower(path), "hdr") {
+		// Radiance HDR decoder has to be registered by importing github.com/mdouchement/hdr/codec/rgbe
+		texture, _, err = image.Decode(textureFile)
+	} else {
+		return nil, fmt.Errorf("%s: unsupported image format", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return texture, nil
+}
+
+// loadTexture returns colors of the image which aren't premultiplied by alpha, and the alpha
+// channel as a gray image, which is nil if the image is opaque
+func loadTexture(texture image.Image) ([][]Color, [][]Color) {
+	width := texture.Bounds().Dx()
+	height := texture.Bounds().Dy()
+	array := make([][]Color, width)
+	alpha := make([][]Color, width)
+	for i := 0; i < width; i++ {
+		array[i] = make([]Color, height)
+		alpha[i] = make([]Color, height)
+	}
+
+	opaque := true
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBA64Model.Convert(texture.At(x, y)).(color.NRGBA64)
+			array[x][y] = Color{float64(c.R>>8) / 255, float64(c.G>>8) / 255, float64(c.B>>8) / 255}
+			a := float64(c.A>>8) / 255
+			alpha[x][y] = Color{a, a, a}
+			opaque = opaque && c.A == 0xffff
+		}
+	}
+
+	if opaque {
+		return array, nil
+	}
+	return array, alpha
+}
+
+// invert returns a copy of the image with every channel v replaced by 1 - v
+func invert(texture [][]Color) [][]Color {
+	inverted := make([][]Color, len(texture))
+	for i, column := range texture {
+		inverted[i] = make([]Color, len(column))
+		for j, c := range column {
+			inverted[i][j] = Color{1 - c.r, 1 - c.g, 1 - c.b}
+		}
+	}
+	return inverted
+}
+
+func getTexture(path string, imageArray *[]ImageHash) ([][]Color, error) {
+	img, err := getImage(path, imageArray)
+	return img.image, err
+}
+
+// getAlpha returns the alpha channel of the image as a gray image, nil if the image is opaque
+func getAlpha(path string, imageArray *[]ImageHash) ([][]Color, error) {
+	img, err := getImage(path, imageArray)
+	return img.alpha, err
+}
+
+// getImage loads the image once and returns it from imageArray later
+func getImage(path string, imageArray *[]ImageHash) (ImageHash, error) {
+	strHash := hash(path)
+	result := wasImageLoaded(strHash, *imageArray)
+	if result == -1 {
+		img, err := loadImage(path)
+		if err != nil {
+			return ImageHash{}, err
+		}
+		texture, alpha := loadTexture(img)
+		*imageArray = append(*imageArray, ImageHash{
+			texture, alpha, strHash,
+		})
+		return (*imageArray)[len(*imageArray)-1], nil
+	}
+	return (*imageArray)[result], nil
+}
+
+// ReadImage loads a PNG, JPEG or Radiance HDR image for use with NewImageUV and NewDiffNormalUV
+func ReadImage(path string) ([][]Color, error) {
+	img, err := loadImage(path)
+	if err != nil {
+		return nil, err
+	}
+	texture, _ := loadTexture(img)
+	return texture, nil
+}
@@ -1,7 +0,0 @@
-package main
-
-type Sphere struct {
-	origin   Tuple
-	radius   float64
-	material Material
-}
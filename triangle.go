@@ -1,14 +0,0 @@
-package main
-
-type TrianglePosition struct {
-	vertex0, vertex1, vertex2 Tuple
-}
-
-type Triangle struct {
-	position TrianglePosition
-	vtexture TrianglePosition
-	vnormals TrianglePosition
-	material Material
-	normal   Tuple
-	smooth   bool
-}